@@ -1,155 +1,121 @@
 package main
 
 import (
-	"flag"
-	as_v2 "k8s.io/api/autoscaling/v2beta1"
+	"context"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/prometheus/common/log"
-	"github.com/shichanson/hpa-exporter/metrics"
+	"github.com/prometheus/exporter-toolkit/web"
 
+	"github.com/shichanson/hpa-exporter/metrics"
+	"github.com/shichanson/hpa-exporter/pkg/config"
+	"github.com/shichanson/hpa-exporter/pkg/logging"
+	"github.com/shichanson/hpa-exporter/pkg/setting"
 )
 
-
-
 func main() {
-	flag.Parse()
-	e := metrics.ValidateFlags()
+	cfg, e := config.New(os.Args[1:])
 	if e != nil {
-		panic(e)
+		os.Exit(2)
+	}
+	logger := logging.New(cfg.LogLevel, cfg.LogFormat)
+
+	if e := metrics.ValidateLoggingTo(cfg.LoggingTo); e != nil {
+		logger.Error("invalid flags", "err", e)
+		os.Exit(1)
 	}
 	time.Local, e = time.LoadLocation("Asia/Tokyo")
 	if e != nil {
 		time.Local = time.FixedZone("Asia/Tokyo", 9*60*60)
 	}
 
-	if *metrics.ConditionLogging {
-		e = metrics.CheckLogGroup()
+	kubeClient, kubeConfig, e := setting.LoadKubeClient(cfg)
+	if e != nil {
+		logger.Error("failed to build Kubernetes client", "err", e)
+		os.Exit(1)
+	}
+	metrics.Configure(kubeClient, kubeConfig)
+
+	var sink metrics.ConditionSink
+	if cfg.ConditionLogging {
+		if cfg.LoggingTo == "cwlogs" {
+			if e := metrics.CheckLogGroup(logger, cfg); e != nil {
+				logger.Error("failed to ensure CloudWatch log group", "err", e)
+				os.Exit(1)
+			}
+		}
+		sink, e = metrics.NewConditionSink(logger, cfg)
 		if e != nil {
-			panic(e)
+			logger.Error("failed to build condition-log sink", "err", e)
+			os.Exit(1)
 		}
+		defer sink.Close()
 	}
 
-	log.Info("start HPA exporter")
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+		<-sig
+		cancel()
+	}()
 
-	if *metrics.ConditionLogging {
+	if cfg.EnableLeaderElection {
+		metrics.SetLeading(false)
 		go func() {
-			for {
-				hpa, err := metrics.GetHpaListV2()
-				if err != nil {
-					log.Errorln(err)
-					continue
-				}
-				if *metrics.LoggingTo == "cwlogs" {
-					metrics.PutHPAConditionToCWLog(hpa)
-				} else {
-					for _, a := range hpa {
-						log.Infoln(metrics.HpaConditionJsonString(a))
-					}
-				}
-				time.Sleep(time.Duration(*metrics.LoggingInterval) * time.Second)
+			if err := metrics.RunLeaderElection(ctx, cfg,
+				func() { logger.Info("acquired leadership") },
+				func() { logger.Info("lost leadership") },
+			); err != nil {
+				logger.Error("leader election stopped", "err", err)
 			}
 		}()
+	} else {
+		metrics.IsLeaderGauge.Set(1)
 	}
 
-	go func() {
-		for {
-			hpa, err := metrics.GetHpaListV2()
-			if err != nil {
-				log.Errorln(err)
-				continue
-			}
-			metrics.ResetAllMetric()
-			for _, a := range hpa {
-				baseLabel := prometheus.Labels{
-					"hpa_name":       a.ObjectMeta.Name,
-					"hpa_namespace":  a.ObjectMeta.Namespace,
-					"ref_kind":       a.Spec.ScaleTargetRef.Kind,
-					"ref_name":       a.Spec.ScaleTargetRef.Name,
-					"ref_apiversion": a.Spec.ScaleTargetRef.APIVersion,
-				}
-
-				metrics.HpaCurrentPodsNum.With(baseLabel).Set(float64(a.Status.CurrentReplicas))
-				metrics.HpaDesiredPodsNum.With(baseLabel).Set(float64(a.Status.DesiredReplicas))
-				if a.Spec.MinReplicas != nil {
-					metrics.HpaMinPodsNum.With(baseLabel).Set(float64(*a.Spec.MinReplicas))
-				}
-				metrics.HpaMaxPodsNum.With(baseLabel).Set(float64(a.Spec.MaxReplicas))
-				if a.Status.LastScaleTime != nil {
-					metrics.HpaLastScaleSecond.With(baseLabel).Set(float64(a.Status.LastScaleTime.Unix()))
-				}
-
-				for _, metric := range a.Spec.Metrics {
-					switch metric.Type {
-					case as_v2.ObjectMetricSourceType:
-						m := metrics.ParseObjectSpec(metric.Object)
-						v, l := metrics.ParseCommonMetrics(m)
-						metrics.HpaTargetMetricsValue.With(metrics.MergeLabels(baseLabel, l)).Set(v)
-					case as_v2.PodsMetricSourceType:
-						m := metrics.ParsePodsSpec(metric.Pods)
-						v, l := metrics.ParseCommonMetrics(m)
-						metrics.HpaTargetMetricsValue.With(metrics.MergeLabels(baseLabel, l)).Set(v)
-					case as_v2.ResourceMetricSourceType:
-						m := metrics.ParseResourceSpec(metric.Resource)
-						v, l := metrics.ParseCommonMetrics(m)
-						metrics.HpaTargetMetricsValue.With(metrics.MergeLabels(baseLabel, l)).Set(v)
-					case as_v2.ExternalMetricSourceType:
-						m := metrics.ParseExternalSpec(metric.External)
-						v, l := metrics.ParseCommonMetrics(m)
-						metrics.HpaTargetMetricsValue.With(metrics.MergeLabels(baseLabel, l)).Set(v)
-					default:
-						continue
-					}
-				}
-
-				for _, metric := range a.Status.CurrentMetrics {
-					switch metric.Type {
-					case as_v2.ObjectMetricSourceType:
-						m := metrics.ParseObjectStatus(metric.Object)
-						v, l := metrics.ParseCommonMetrics(m)
-						metrics.HpaCurrentMetricsValue.With(metrics.MergeLabels(baseLabel, l)).Set(v)
-					case as_v2.PodsMetricSourceType:
-						m := metrics.ParsePodsStatus(metric.Pods)
-						v, l := metrics.ParseCommonMetrics(m)
-						metrics.HpaCurrentMetricsValue.With(metrics.MergeLabels(baseLabel, l)).Set(v)
-					case as_v2.ResourceMetricSourceType:
-						m := metrics.ParseResourceStatus(metric.Resource)
-						v, l := metrics.ParseCommonMetrics(m)
-						metrics.HpaCurrentMetricsValue.With(metrics.MergeLabels(baseLabel, l)).Set(v)
-					case as_v2.ExternalMetricSourceType:
-						m := metrics.ParseExternalStatus(metric.External)
-						v, l := metrics.ParseCommonMetrics(m)
-						metrics.HpaCurrentMetricsValue.With(metrics.MergeLabels(baseLabel, l)).Set(v)
-					default:
-						continue
-					}
-				}
-
-				for _, cond := range a.Status.Conditions {
-					annoLabel, annoLabelRev := metrics.MakeAnnotationCondLabels(cond)
-					switch cond.Type {
-					case as_v2.AbleToScale:
-						metrics.HpaAbleToScale.With(metrics.MergeLabels(baseLabel, annoLabel)).Set(float64(1))
-						metrics.HpaAbleToScale.With(metrics.MergeLabels(baseLabel, annoLabelRev)).Set(float64(0))
-					case as_v2.ScalingActive:
-						metrics.HpaScalingActive.With(metrics.MergeLabels(baseLabel, annoLabel)).Set(float64(1))
-						metrics.HpaScalingActive.With(metrics.MergeLabels(baseLabel, annoLabelRev)).Set(float64(0))
-					case as_v2.ScalingLimited:
-						metrics.HpaScalingLimited.With(metrics.MergeLabels(baseLabel, annoLabel)).Set(float64(1))
-						metrics.HpaScalingLimited.With(metrics.MergeLabels(baseLabel, annoLabelRev)).Set(float64(0))
-					}
-				}
-			}
-			time.Sleep(time.Duration(*metrics.MetricsInterval) * time.Second)
-		}
-	}()
-	http.Handle("/metrics", promhttp.Handler())
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	informerSet := metrics.NewHPAInformerSet(cfg)
+	if cfg.ConditionLogging {
+		metrics.RegisterConditionEventHandlers(ctx, logger, informerSet, sink)
+	}
+
+	prometheus.MustRegister(metrics.NewHPACollector(informerSet.Lister, informerSet.Selector))
+
+	crConfig, e := metrics.LoadCustomResourceConfig(cfg)
+	if e != nil {
+		logger.Error("failed to load custom-resource config", "err", e)
+		os.Exit(1)
+	}
+	crInformerSet, e := metrics.NewCustomResourceInformerSet(crConfig, cfg.ResyncPeriod, prometheus.DefaultRegisterer)
+	if e != nil {
+		logger.Error("failed to build custom-resource informers", "err", e)
+		os.Exit(1)
+	}
+
+	informerSet.Factory.Start(ctx.Done())
+	informerSet.Factory.WaitForCacheSync(ctx.Done())
+	crInformerSet.Factory.Start(ctx.Done())
+
+	logger.Info("start HPA exporter")
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(metrics.RootDoc))
 	})
 
-	log.Fatal(http.ListenAndServe(*metrics.Addr, nil))
+	srv := &http.Server{Handler: mux}
+	flagConfig := &web.FlagConfig{
+		WebListenAddresses: &[]string{cfg.ListenAddress},
+		WebConfigFile:      &cfg.WebConfigFile,
+	}
+	if err := web.ListenAndServe(srv, flagConfig, logger); err != nil {
+		logger.Error("http server exited", "err", err)
+		os.Exit(1)
+	}
 }
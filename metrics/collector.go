@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	as_v2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var (
+	descHpaCurrentPodsNum = prometheus.NewDesc(
+		"hpa_current_pods_num", "Number of current pods by status.", baseLabels, nil)
+	descHpaDesiredPodsNum = prometheus.NewDesc(
+		"hpa_desired_pods_num", "Number of desired pods by status.", baseLabels, nil)
+	descHpaMinPodsNum = prometheus.NewDesc(
+		"hpa_min_pods_num", "Number of min pods by spec.", baseLabels, nil)
+	descHpaMaxPodsNum = prometheus.NewDesc(
+		"hpa_max_pods_num", "Number of max pods by spec.", baseLabels, nil)
+	descHpaLastScaleSecond = prometheus.NewDesc(
+		"hpa_last_scale_second", "Time the scale was last executed.", baseLabels, nil)
+	descHpaCurrentMetricsValue = prometheus.NewDesc(
+		"hpa_current_metrics_value", "Current Metrics Value.", append(baseLabels, metricLabels...), nil)
+	descHpaTargetMetricsValue = prometheus.NewDesc(
+		"hpa_target_metrics_value", "Target Metrics Value.", append(baseLabels, metricLabels...), nil)
+	descHpaAbleToScale = prometheus.NewDesc(
+		"hpa_able_to_scale", "status able to scale from annotation.", append(baseLabels, annoLabels...), nil)
+	descHpaScalingActive = prometheus.NewDesc(
+		"hpa_scaling_active", "status scaling active from annotation.", append(baseLabels, annoLabels...), nil)
+	descHpaScalingLimited = prometheus.NewDesc(
+		"hpa_scaling_limited", "status scaling limited from annotation.", append(baseLabels, annoLabels...), nil)
+	descHpaContainerResourceTarget = prometheus.NewDesc(
+		"hpa_container_resource_target", "Target value of a per-container resource metric.",
+		append(append(baseLabels, metricLabels...), containerResourceLabels...), nil)
+	descHpaScaleUpStabilizationWindowSeconds = prometheus.NewDesc(
+		"hpa_scale_up_stabilization_window_seconds", "Stabilization window applied to scale-up decisions by the HPA behavior.", baseLabels, nil)
+	descHpaScaleDownStabilizationWindowSeconds = prometheus.NewDesc(
+		"hpa_scale_down_stabilization_window_seconds", "Stabilization window applied to scale-down decisions by the HPA behavior.", baseLabels, nil)
+	descHpaScalePolicyValue = prometheus.NewDesc(
+		"hpa_scale_policy_value", "Value of a scaling policy from the HPA behavior, by direction, policy type and select policy.",
+		append(baseLabels, scalePolicyLabels...), nil)
+	descHpaScalePolicyPeriodSeconds = prometheus.NewDesc(
+		"hpa_scale_policy_period_seconds", "Period, in seconds, over which a scaling policy from the HPA behavior applies.",
+		append(baseLabels, scalePolicyLabels...), nil)
+
+	allDescs = []*prometheus.Desc{
+		descHpaCurrentPodsNum,
+		descHpaDesiredPodsNum,
+		descHpaMinPodsNum,
+		descHpaMaxPodsNum,
+		descHpaLastScaleSecond,
+		descHpaCurrentMetricsValue,
+		descHpaTargetMetricsValue,
+		descHpaAbleToScale,
+		descHpaScalingActive,
+		descHpaScalingLimited,
+		descHpaContainerResourceTarget,
+		descHpaScaleUpStabilizationWindowSeconds,
+		descHpaScaleDownStabilizationWindowSeconds,
+		descHpaScalePolicyValue,
+		descHpaScalePolicyPeriodSeconds,
+	}
+)
+
+// HPACollector implements prometheus.Collector by reading straight from an
+// informer's lister on every scrape, instead of maintaining its own set of
+// GaugeVecs. This removes the Reset()/re-list race the old polling loop had
+// with concurrent scrapes, and scales to clusters with thousands of HPAs
+// since Collect only walks the in-memory cache.
+type HPACollector struct {
+	lister   hpaLister
+	selector labels.Selector
+}
+
+// NewHPACollector builds a collector backed by the given lister, applying
+// selector as an extra client-side filter on top of whatever the informer
+// itself already watches.
+func NewHPACollector(lister hpaLister, selector labels.Selector) *HPACollector {
+	return &HPACollector{lister: lister, selector: selector}
+}
+
+func (c *HPACollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range allDescs {
+		ch <- d
+	}
+}
+
+func (c *HPACollector) Collect(ch chan<- prometheus.Metric) {
+	hpas, err := c.lister.List(c.selector)
+	if err != nil {
+		return
+	}
+	for _, a := range hpas {
+		c.collectOne(ch, a)
+	}
+}
+
+func (c *HPACollector) collectOne(ch chan<- prometheus.Metric, a as_v2.HorizontalPodAutoscaler) {
+	labelValues := []string{
+		a.ObjectMeta.Name,
+		a.ObjectMeta.Namespace,
+		a.Spec.ScaleTargetRef.Kind,
+		a.Spec.ScaleTargetRef.Name,
+		a.Spec.ScaleTargetRef.APIVersion,
+	}
+
+	ch <- prometheus.MustNewConstMetric(descHpaCurrentPodsNum, prometheus.GaugeValue, float64(a.Status.CurrentReplicas), labelValues...)
+	ch <- prometheus.MustNewConstMetric(descHpaDesiredPodsNum, prometheus.GaugeValue, float64(a.Status.DesiredReplicas), labelValues...)
+	if a.Spec.MinReplicas != nil {
+		ch <- prometheus.MustNewConstMetric(descHpaMinPodsNum, prometheus.GaugeValue, float64(*a.Spec.MinReplicas), labelValues...)
+	}
+	ch <- prometheus.MustNewConstMetric(descHpaMaxPodsNum, prometheus.GaugeValue, float64(a.Spec.MaxReplicas), labelValues...)
+	if a.Status.LastScaleTime != nil {
+		ch <- prometheus.MustNewConstMetric(descHpaLastScaleSecond, prometheus.GaugeValue, float64(a.Status.LastScaleTime.Unix()), labelValues...)
+	}
+
+	scaleUpWindow, scaleDownWindow, policies := ParseBehavior(a.Spec.Behavior)
+	if scaleUpWindow != nil {
+		ch <- prometheus.MustNewConstMetric(descHpaScaleUpStabilizationWindowSeconds, prometheus.GaugeValue, float64(*scaleUpWindow), labelValues...)
+	}
+	if scaleDownWindow != nil {
+		ch <- prometheus.MustNewConstMetric(descHpaScaleDownStabilizationWindowSeconds, prometheus.GaugeValue, float64(*scaleDownWindow), labelValues...)
+	}
+	for _, p := range policies {
+		policyValues := append(append([]string{}, labelValues...), p.Direction, p.PolicyType, p.SelectPolicy)
+		ch <- prometheus.MustNewConstMetric(descHpaScalePolicyValue, prometheus.GaugeValue, float64(p.Value), policyValues...)
+		ch <- prometheus.MustNewConstMetric(descHpaScalePolicyPeriodSeconds, prometheus.GaugeValue, float64(p.Period), policyValues...)
+	}
+
+	for _, metric := range a.Spec.Metrics {
+		switch metric.Type {
+		case as_v2.ObjectMetricSourceType:
+			m := ParseObjectSpec(metric.Object)
+			v, l := ParseCommonMetrics(m)
+			ch <- prometheus.MustNewConstMetric(descHpaTargetMetricsValue, prometheus.GaugeValue, v, append(labelValues, l["metric_kind"], l["metric_name"], l["metric_metricname"])...)
+		case as_v2.PodsMetricSourceType:
+			m := ParsePodsSpec(metric.Pods)
+			v, l := ParseCommonMetrics(m)
+			ch <- prometheus.MustNewConstMetric(descHpaTargetMetricsValue, prometheus.GaugeValue, v, append(labelValues, l["metric_kind"], l["metric_name"], l["metric_metricname"])...)
+		case as_v2.ResourceMetricSourceType:
+			m := ParseResourceSpec(metric.Resource)
+			v, l := ParseCommonMetrics(m)
+			ch <- prometheus.MustNewConstMetric(descHpaTargetMetricsValue, prometheus.GaugeValue, v, append(labelValues, l["metric_kind"], l["metric_name"], l["metric_metricname"])...)
+		case as_v2.ContainerResourceMetricSourceType:
+			m, container := ParseContainerResourceSpec(metric.ContainerResource)
+			v, l := ParseCommonMetrics(m)
+			ch <- prometheus.MustNewConstMetric(descHpaContainerResourceTarget, prometheus.GaugeValue, v, append(labelValues, l["metric_kind"], l["metric_name"], l["metric_metricname"], container)...)
+		case as_v2.ExternalMetricSourceType:
+			m := ParseExternalSpec(metric.External)
+			v, l := ParseCommonMetrics(m)
+			ch <- prometheus.MustNewConstMetric(descHpaTargetMetricsValue, prometheus.GaugeValue, v, append(labelValues, l["metric_kind"], l["metric_name"], l["metric_metricname"])...)
+		}
+	}
+
+	for _, metric := range a.Status.CurrentMetrics {
+		switch metric.Type {
+		case as_v2.ObjectMetricSourceType:
+			m := ParseObjectStatus(metric.Object)
+			v, l := ParseCommonMetrics(m)
+			ch <- prometheus.MustNewConstMetric(descHpaCurrentMetricsValue, prometheus.GaugeValue, v, append(labelValues, l["metric_kind"], l["metric_name"], l["metric_metricname"])...)
+		case as_v2.PodsMetricSourceType:
+			m := ParsePodsStatus(metric.Pods)
+			v, l := ParseCommonMetrics(m)
+			ch <- prometheus.MustNewConstMetric(descHpaCurrentMetricsValue, prometheus.GaugeValue, v, append(labelValues, l["metric_kind"], l["metric_name"], l["metric_metricname"])...)
+		case as_v2.ResourceMetricSourceType:
+			m := ParseResourceStatus(metric.Resource)
+			v, l := ParseCommonMetrics(m)
+			ch <- prometheus.MustNewConstMetric(descHpaCurrentMetricsValue, prometheus.GaugeValue, v, append(labelValues, l["metric_kind"], l["metric_name"], l["metric_metricname"])...)
+		case as_v2.ExternalMetricSourceType:
+			m := ParseExternalStatus(metric.External)
+			v, l := ParseCommonMetrics(m)
+			ch <- prometheus.MustNewConstMetric(descHpaCurrentMetricsValue, prometheus.GaugeValue, v, append(labelValues, l["metric_kind"], l["metric_name"], l["metric_metricname"])...)
+		}
+	}
+
+	for _, cond := range a.Status.Conditions {
+		annoLabel, annoLabelRev := MakeAnnotationCondLabels(cond)
+		var desc *prometheus.Desc
+		switch cond.Type {
+		case as_v2.AbleToScale:
+			desc = descHpaAbleToScale
+		case as_v2.ScalingActive:
+			desc = descHpaScalingActive
+		case as_v2.ScalingLimited:
+			desc = descHpaScalingLimited
+		default:
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, append(labelValues, annoLabel["cond_status"], annoLabel["cond_reason"], annoLabel["cond_message"])...)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 0, append(labelValues, annoLabelRev["cond_status"], annoLabelRev["cond_reason"], annoLabelRev["cond_message"])...)
+	}
+}
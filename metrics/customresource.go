@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+
+	"github.com/shichanson/hpa-exporter/pkg/config"
+)
+
+// CustomResourceConfig is a customresourcestate-style config: one entry per
+// GVK, with JSONPath selectors picking the same fields the native HPA
+// metrics expose (current/desired/min/max/lastScaleTime/conditions).
+type CustomResourceConfig struct {
+	Resources []CustomResourceSpec `json:"resources"`
+}
+
+// CustomResourceSpec describes one watched GVK and how to pull HPA-shaped
+// metrics out of it. Selector fields are JSONPath template strings
+// (e.g. "{.status.replicas}"); an empty selector is simply skipped.
+type CustomResourceSpec struct {
+	GroupVersionKind CustomResourceGVK `json:"groupVersionKind"`
+	Resource         string            `json:"resource"`
+	MetricNamePrefix string            `json:"metricNamePrefix"`
+	RefKind          string            `json:"refKind"`
+	RefName          string            `json:"refName"`
+	RefAPIVersion    string            `json:"refApiVersion"`
+	Current          string            `json:"current"`
+	Desired          string            `json:"desired"`
+	Min              string            `json:"min"`
+	Max              string            `json:"max"`
+	LastScaleTime    string            `json:"lastScaleTime"`
+	Conditions       []string          `json:"conditions"`
+}
+
+type CustomResourceGVK struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+func (g CustomResourceGVK) schemaGVK() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: g.Group, Version: g.Version, Kind: g.Kind}
+}
+
+func (s CustomResourceSpec) groupVersionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: s.GroupVersionKind.Group, Version: s.GroupVersionKind.Version, Resource: s.Resource}
+}
+
+// defaultCustomResourceConfigYAML ships KEDA ScaledObject and VPA metrics
+// out of the box, with zero config file required.
+const defaultCustomResourceConfigYAML = `
+resources:
+  - groupVersionKind:
+      group: keda.sh
+      version: v1alpha1
+      kind: ScaledObject
+    resource: scaledobjects
+    metricNamePrefix: keda_scaledobject
+    refKind: "{.spec.scaleTargetRef.kind}"
+    refName: "{.spec.scaleTargetRef.name}"
+    refApiVersion: "{.spec.scaleTargetRef.apiVersion}"
+    current: "{.status.replicas}"
+    min: "{.spec.minReplicaCount}"
+    max: "{.spec.maxReplicaCount}"
+    conditions:
+      - "{.status.conditions}"
+  - groupVersionKind:
+      group: autoscaling.k8s.io
+      version: v1
+      kind: VerticalPodAutoscaler
+    resource: verticalpodautoscalers
+    metricNamePrefix: vpa
+    refKind: "{.spec.targetRef.kind}"
+    refName: "{.spec.targetRef.name}"
+    refApiVersion: "{.spec.targetRef.apiVersion}"
+    conditions:
+      - "{.status.conditions}"
+`
+
+// LoadCustomResourceConfig reads cfg.CustomResourceConfigFile, falling back
+// to the built-in KEDA/VPA config when it's unset.
+func LoadCustomResourceConfig(cfg *config.Config) (*CustomResourceConfig, error) {
+	raw := []byte(defaultCustomResourceConfigYAML)
+	if cfg.CustomResourceConfigFile != "" {
+		b, err := os.ReadFile(cfg.CustomResourceConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		raw = b
+	}
+	out := &CustomResourceConfig{}
+	if err := yaml.Unmarshal(raw, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// evalJSONPath renders expr against obj (typically an unstructured object's
+// content map), returning false if expr is empty or the path is missing.
+func evalJSONPath(expr string, obj map[string]interface{}) (string, bool) {
+	if expr == "" {
+		return "", false
+	}
+	jp := jsonpath.New("customResourceState").AllowMissingKeys(true)
+	if err := jp.Parse(expr); err != nil {
+		return "", false
+	}
+	buf := new(bytes.Buffer)
+	if err := jp.Execute(buf, obj); err != nil || buf.Len() == 0 {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+func evalJSONPathFloat(expr string, obj map[string]interface{}) (float64, bool) {
+	s, ok := evalJSONPath(expr, obj)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+func evalJSONPathTime(expr string, obj map[string]interface{}) (time.Time, bool) {
+	s, ok := evalJSONPath(expr, obj)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// evalJSONPathSlice evaluates expr and returns it as a []interface{},
+// used for the conditions selector which points at a whole condition list.
+func evalJSONPathSlice(expr string, obj map[string]interface{}) ([]interface{}, bool) {
+	if expr == "" {
+		return nil, false
+	}
+	jp := jsonpath.New("customResourceState").AllowMissingKeys(true)
+	if err := jp.Parse(expr); err != nil {
+		return nil, false
+	}
+	results, err := jp.FindResults(obj)
+	if err != nil || len(results) == 0 || len(results[0]) == 0 {
+		return nil, false
+	}
+	v := results[0][0].Interface()
+	conds, ok := v.([]interface{})
+	return conds, ok
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// customResourceMetrics is the set of gauge vecs registered for one
+// CustomResourceSpec. The replica and last-scale gauges reuse the native
+// hpa_* metric suffixes and base labels under the spec's MetricNamePrefix;
+// conditions are exported as a single ${prefix}_condition gauge labeled by
+// cond_type rather than one gauge per condition type.
+type customResourceMetrics struct {
+	spec      CustomResourceSpec
+	current   *prometheus.GaugeVec
+	desired   *prometheus.GaugeVec
+	min       *prometheus.GaugeVec
+	max       *prometheus.GaugeVec
+	lastScale *prometheus.GaugeVec
+	condition *prometheus.GaugeVec
+
+	mu            sync.Mutex
+	conditionRows map[string]map[string][]string // object key -> condition label values, keyed by their joined form
+}
+
+func newCustomResourceMetrics(spec CustomResourceSpec) *customResourceMetrics {
+	condLabels := append(append([]string{}, baseLabels...), "cond_type", "cond_status", "cond_reason", "cond_message")
+	kind := spec.GroupVersionKind.Kind
+	return &customResourceMetrics{
+		spec:          spec,
+		current:       prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: spec.MetricNamePrefix + "_current_pods_num", Help: "Current number of replicas reported by the " + kind + "."}, baseLabels),
+		desired:       prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: spec.MetricNamePrefix + "_desired_pods_num", Help: "Desired number of replicas reported by the " + kind + "."}, baseLabels),
+		min:           prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: spec.MetricNamePrefix + "_min_pods_num", Help: "Minimum number of replicas reported by the " + kind + "."}, baseLabels),
+		max:           prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: spec.MetricNamePrefix + "_max_pods_num", Help: "Maximum number of replicas reported by the " + kind + "."}, baseLabels),
+		lastScale:     prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: spec.MetricNamePrefix + "_last_scale_second", Help: "Unix timestamp of the last scale reported by the " + kind + "."}, baseLabels),
+		condition:     prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: spec.MetricNamePrefix + "_condition", Help: "1 for each condition currently reported by the " + kind + "; series for conditions no longer reported are removed."}, condLabels),
+		conditionRows: make(map[string]map[string][]string),
+	}
+}
+
+func (m *customResourceMetrics) MustRegister(reg prometheus.Registerer) {
+	reg.MustRegister(m.current, m.desired, m.min, m.max, m.lastScale, m.condition)
+}
+
+func (m *customResourceMetrics) labelValues(obj *unstructured.Unstructured, content map[string]interface{}) []string {
+	refKind, _ := evalJSONPath(m.spec.RefKind, content)
+	refName, _ := evalJSONPath(m.spec.RefName, content)
+	refAPIVersion, _ := evalJSONPath(m.spec.RefAPIVersion, content)
+	return []string{obj.GetName(), obj.GetNamespace(), refKind, refName, refAPIVersion}
+}
+
+// objectKey identifies one custom resource across update/delete calls, for
+// tracking which condition rows it currently has exported.
+func objectKey(obj *unstructured.Unstructured) string {
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+func (m *customResourceMetrics) update(obj *unstructured.Unstructured) {
+	content := obj.UnstructuredContent()
+	labels := m.labelValues(obj, content)
+
+	if v, ok := evalJSONPathFloat(m.spec.Current, content); ok {
+		m.current.WithLabelValues(labels...).Set(v)
+	}
+	if v, ok := evalJSONPathFloat(m.spec.Desired, content); ok {
+		m.desired.WithLabelValues(labels...).Set(v)
+	}
+	if v, ok := evalJSONPathFloat(m.spec.Min, content); ok {
+		m.min.WithLabelValues(labels...).Set(v)
+	}
+	if v, ok := evalJSONPathFloat(m.spec.Max, content); ok {
+		m.max.WithLabelValues(labels...).Set(v)
+	}
+	if t, ok := evalJSONPathTime(m.spec.LastScaleTime, content); ok {
+		m.lastScale.WithLabelValues(labels...).Set(float64(t.Unix()))
+	}
+
+	rows := make(map[string][]string)
+	for _, condPath := range m.spec.Conditions {
+		conds, ok := evalJSONPathSlice(condPath, content)
+		if !ok {
+			continue
+		}
+		for _, c := range conds {
+			cond, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			condLabels := append(append([]string{}, labels...),
+				stringField(cond, "type"), stringField(cond, "status"), stringField(cond, "reason"), stringField(cond, "message"))
+			m.condition.WithLabelValues(condLabels...).Set(1)
+			rows[strings.Join(condLabels, "\x00")] = condLabels
+		}
+	}
+
+	key := objectKey(obj)
+	m.mu.Lock()
+	for rowKey, staleLabels := range m.conditionRows[key] {
+		if _, stillPresent := rows[rowKey]; !stillPresent {
+			m.condition.DeleteLabelValues(staleLabels...)
+		}
+	}
+	m.conditionRows[key] = rows
+	m.mu.Unlock()
+}
+
+func (m *customResourceMetrics) delete(obj *unstructured.Unstructured) {
+	content := obj.UnstructuredContent()
+	labels := m.labelValues(obj, content)
+	m.current.DeleteLabelValues(labels...)
+	m.desired.DeleteLabelValues(labels...)
+	m.min.DeleteLabelValues(labels...)
+	m.max.DeleteLabelValues(labels...)
+	m.lastScale.DeleteLabelValues(labels...)
+
+	key := objectKey(obj)
+	m.mu.Lock()
+	for _, condLabels := range m.conditionRows[key] {
+		m.condition.DeleteLabelValues(condLabels...)
+	}
+	delete(m.conditionRows, key)
+	m.mu.Unlock()
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
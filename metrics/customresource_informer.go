@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CustomResourceInformerSet bundles the dynamic informer factory watching
+// every GVK in a CustomResourceConfig, mirroring HPAInformerSet for the
+// native HPA path.
+type CustomResourceInformerSet struct {
+	Factory dynamicinformer.DynamicSharedInformerFactory
+}
+
+// NewCustomResourceInformerSet builds a dynamic informer per configured
+// GVK, registers its gauge vecs with reg, and wires Add/Update/Delete to
+// keep them current.
+func NewCustomResourceInformerSet(crCfg *CustomResourceConfig, resyncPeriod time.Duration, reg prometheus.Registerer) (*CustomResourceInformerSet, error) {
+	dynClient, err := dynamic.NewForConfig(kubeRESTConfig)
+	if err != nil {
+		return nil, err
+	}
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(dynClient, resyncPeriod)
+
+	for _, spec := range crCfg.Resources {
+		metrics := newCustomResourceMetrics(spec)
+		metrics.MustRegister(reg)
+
+		informer := factory.ForResource(spec.groupVersionResource()).Informer()
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				if u, ok := obj.(*unstructured.Unstructured); ok {
+					metrics.update(u)
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				if u, ok := newObj.(*unstructured.Unstructured); ok {
+					metrics.update(u)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				if u, ok := obj.(*unstructured.Unstructured); ok {
+					metrics.delete(u)
+				}
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					if u, ok := tombstone.Obj.(*unstructured.Unstructured); ok {
+						metrics.delete(u)
+					}
+				}
+			},
+		})
+	}
+
+	return &CustomResourceInformerSet{Factory: factory}, nil
+}
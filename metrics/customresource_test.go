@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvalJSONPath(t *testing.T) {
+	obj := map[string]interface{}{
+		"status": map[string]interface{}{
+			"replicas": float64(3),
+		},
+	}
+
+	cases := []struct {
+		name   string
+		expr   string
+		want   string
+		wantOk bool
+	}{
+		{name: "empty expr", expr: "", want: "", wantOk: false},
+		{name: "missing path", expr: "{.status.desired}", want: "", wantOk: false},
+		{name: "invalid syntax", expr: "{.status[", want: "", wantOk: false},
+		{name: "present path", expr: "{.status.replicas}", want: "3", wantOk: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := evalJSONPath(tc.expr, obj)
+			if ok != tc.wantOk || got != tc.want {
+				t.Errorf("evalJSONPath(%q) = (%q, %v), want (%q, %v)", tc.expr, got, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}
+
+func TestEvalJSONPathFloat(t *testing.T) {
+	obj := map[string]interface{}{
+		"status": map[string]interface{}{
+			"replicas": float64(3),
+			"name":     "not-a-number",
+		},
+	}
+
+	cases := []struct {
+		name   string
+		expr   string
+		want   float64
+		wantOk bool
+	}{
+		{name: "missing path", expr: "{.status.desired}", want: 0, wantOk: false},
+		{name: "non-numeric value", expr: "{.status.name}", want: 0, wantOk: false},
+		{name: "numeric value", expr: "{.status.replicas}", want: 3, wantOk: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := evalJSONPathFloat(tc.expr, obj)
+			if ok != tc.wantOk || got != tc.want {
+				t.Errorf("evalJSONPathFloat(%q) = (%v, %v), want (%v, %v)", tc.expr, got, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}
+
+func TestEvalJSONPathTime(t *testing.T) {
+	obj := map[string]interface{}{
+		"status": map[string]interface{}{
+			"lastScaleTime": "2026-01-02T03:04:05Z",
+			"name":          "not-a-time",
+		},
+	}
+	want, _ := time.Parse(time.RFC3339, "2026-01-02T03:04:05Z")
+
+	cases := []struct {
+		name   string
+		expr   string
+		want   time.Time
+		wantOk bool
+	}{
+		{name: "missing path", expr: "{.status.desired}", want: time.Time{}, wantOk: false},
+		{name: "non-RFC3339 value", expr: "{.status.name}", want: time.Time{}, wantOk: false},
+		{name: "valid timestamp", expr: "{.status.lastScaleTime}", want: want, wantOk: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := evalJSONPathTime(tc.expr, obj)
+			if ok != tc.wantOk || !got.Equal(tc.want) {
+				t.Errorf("evalJSONPathTime(%q) = (%v, %v), want (%v, %v)", tc.expr, got, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}
+
+func TestEvalJSONPathSlice(t *testing.T) {
+	obj := map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True"},
+			},
+			"replicas": float64(3),
+		},
+	}
+
+	cases := []struct {
+		name   string
+		expr   string
+		want   []interface{}
+		wantOk bool
+	}{
+		{name: "empty expr", expr: "", want: nil, wantOk: false},
+		{name: "missing path", expr: "{.status.missing}", want: nil, wantOk: false},
+		{name: "not a slice", expr: "{.status.replicas}", want: nil, wantOk: false},
+		{
+			name: "present slice",
+			expr: "{.status.conditions}",
+			want: []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True"},
+			},
+			wantOk: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := evalJSONPathSlice(tc.expr, obj)
+			if ok != tc.wantOk || !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("evalJSONPathSlice(%q) = (%v, %v), want (%v, %v)", tc.expr, got, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}
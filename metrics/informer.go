@@ -0,0 +1,222 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	as_v1 "k8s.io/api/autoscaling/v1"
+	as_v2 "k8s.io/api/autoscaling/v2"
+	as_v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	v1listers "k8s.io/client-go/listers/autoscaling/v1"
+	v2listers "k8s.io/client-go/listers/autoscaling/v2"
+	v2beta2listers "k8s.io/client-go/listers/autoscaling/v2beta2"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/shichanson/hpa-exporter/pkg/config"
+)
+
+// conditionBatchInterval bounds how long a condition event waits before
+// being flushed to the sink. Batching trades a little latency for far
+// fewer sink round trips (e.g. CloudWatch's DescribeLogStreams/
+// PutLogEvents pair) when many HPAs change at once, such as a full
+// resync across a cluster with thousands of HPAs.
+const conditionBatchInterval = 10 * time.Second
+
+func labelSelector(expr string) labels.Selector {
+	if expr == "" {
+		return labels.Everything()
+	}
+	sel, err := labels.Parse(expr)
+	if err != nil {
+		return labels.Everything()
+	}
+	return sel
+}
+
+// hpaLister lists HorizontalPodAutoscalers normalized to the v2 type,
+// regardless of which autoscaling group version the informer actually
+// watches.
+type hpaLister interface {
+	List(selector labels.Selector) ([]as_v2.HorizontalPodAutoscaler, error)
+}
+
+type v2Lister struct {
+	inner v2listers.HorizontalPodAutoscalerLister
+}
+
+func (l v2Lister) List(selector labels.Selector) ([]as_v2.HorizontalPodAutoscaler, error) {
+	items, err := l.inner.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]as_v2.HorizontalPodAutoscaler, len(items))
+	for i, it := range items {
+		out[i] = *it
+	}
+	return out, nil
+}
+
+type v2beta2Lister struct {
+	inner v2beta2listers.HorizontalPodAutoscalerLister
+}
+
+func (l v2beta2Lister) List(selector labels.Selector) ([]as_v2.HorizontalPodAutoscaler, error) {
+	items, err := l.inner.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]as_v2.HorizontalPodAutoscaler, len(items))
+	for i, it := range items {
+		out[i] = convertV2beta2ToV2(*it)
+	}
+	return out, nil
+}
+
+type v1Lister struct {
+	inner v1listers.HorizontalPodAutoscalerLister
+}
+
+func (l v1Lister) List(selector labels.Selector) ([]as_v2.HorizontalPodAutoscaler, error) {
+	items, err := l.inner.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]as_v2.HorizontalPodAutoscaler, len(items))
+	for i, it := range items {
+		out[i] = convertV1ToV2(*it)
+	}
+	return out, nil
+}
+
+// HPAInformerSet bundles the SharedInformerFactory driving both the metrics
+// collector and the condition-logging event handlers, picked for whichever
+// autoscaling group version the cluster actually serves.
+type HPAInformerSet struct {
+	Factory  informers.SharedInformerFactory
+	Informer cache.SharedIndexInformer
+	Lister   hpaLister
+	Selector labels.Selector
+}
+
+// NewHPAInformerSet builds the factory and registers an HPA informer for
+// the resolved autoscaling API group version, honoring cfg.Namespace and
+// cfg.LabelSelector.
+func NewHPAInformerSet(cfg *config.Config) *HPAInformerSet {
+	opts := []informers.SharedInformerOption{}
+	if cfg.Namespace != "" {
+		opts = append(opts, informers.WithNamespace(cfg.Namespace))
+	}
+	if cfg.LabelSelector != "" {
+		selector := cfg.LabelSelector
+		opts = append(opts, informers.WithTweakListOptions(func(o *meta_v1.ListOptions) {
+			o.LabelSelector = selector
+		}))
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(kubeClient, cfg.ResyncPeriod, opts...)
+	selector := labelSelector(cfg.LabelSelector)
+
+	switch resolveAutoscalingAPIVersion() {
+	case autoscalingV2beta2:
+		inf := factory.Autoscaling().V2beta2().HorizontalPodAutoscalers()
+		return &HPAInformerSet{Factory: factory, Informer: inf.Informer(), Lister: v2beta2Lister{inf.Lister()}, Selector: selector}
+	case autoscalingV1:
+		inf := factory.Autoscaling().V1().HorizontalPodAutoscalers()
+		return &HPAInformerSet{Factory: factory, Informer: inf.Informer(), Lister: v1Lister{inf.Lister()}, Selector: selector}
+	default:
+		inf := factory.Autoscaling().V2().HorizontalPodAutoscalers()
+		return &HPAInformerSet{Factory: factory, Informer: inf.Informer(), Lister: v2Lister{inf.Lister()}, Selector: selector}
+	}
+}
+
+// normalizeHPA converts whichever typed object the informer handed to an
+// event handler into the v2 shape, so condition logging is version-agnostic.
+func normalizeHPA(obj interface{}) (as_v2.HorizontalPodAutoscaler, bool) {
+	switch v := obj.(type) {
+	case *as_v2.HorizontalPodAutoscaler:
+		return *v, true
+	case *as_v2beta2.HorizontalPodAutoscaler:
+		return convertV2beta2ToV2(*v), true
+	case *as_v1.HorizontalPodAutoscaler:
+		return convertV1ToV2(*v), true
+	default:
+		return as_v2.HorizontalPodAutoscaler{}, false
+	}
+}
+
+// RegisterConditionEventHandlers wires HPA condition logging to informer
+// add/update events instead of the old re-list-on-a-timer loop, batching
+// events over conditionBatchInterval before each call to sink.Emit so a
+// burst of updates costs one sink round trip instead of one per HPA. The
+// batcher's flush loop stops when ctx is canceled.
+func RegisterConditionEventHandlers(ctx context.Context, logger *slog.Logger, informerSet *HPAInformerSet, sink ConditionSink) {
+	batcher := newConditionBatcher(ctx, logger, sink, conditionBatchInterval)
+	informerSet.Informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { logHPACondition(batcher, obj) },
+		UpdateFunc: func(oldObj, newObj interface{}) { logHPACondition(batcher, newObj) },
+	})
+}
+
+func logHPACondition(batcher *conditionBatcher, obj interface{}) {
+	if !isLeading() {
+		return
+	}
+	a, ok := normalizeHPA(obj)
+	if !ok {
+		return
+	}
+	batcher.add(conditionEventFromHPA(a))
+}
+
+// conditionBatcher accumulates HPA condition events from informer callbacks
+// and flushes them to a ConditionSink as one batch per tick.
+type conditionBatcher struct {
+	logger *slog.Logger
+	sink   ConditionSink
+
+	mu      sync.Mutex
+	pending []HPAConditionEvent
+}
+
+func newConditionBatcher(ctx context.Context, logger *slog.Logger, sink ConditionSink, interval time.Duration) *conditionBatcher {
+	b := &conditionBatcher{logger: logger, sink: sink}
+	go b.run(ctx, interval)
+	return b
+}
+
+func (b *conditionBatcher) add(event HPAConditionEvent) {
+	b.mu.Lock()
+	b.pending = append(b.pending, event)
+	b.mu.Unlock()
+}
+
+func (b *conditionBatcher) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush(ctx)
+		case <-ctx.Done():
+			b.flush(context.Background())
+			return
+		}
+	}
+}
+
+func (b *conditionBatcher) flush(ctx context.Context) {
+	b.mu.Lock()
+	events := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+	if len(events) == 0 {
+		return
+	}
+	if err := b.sink.Emit(ctx, events); err != nil {
+		b.logger.Error("failed to emit HPA condition events", slog.Int("count", len(events)), slog.Any("err", err))
+	}
+}
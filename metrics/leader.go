@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/shichanson/hpa-exporter/pkg/config"
+)
+
+// IsLeaderGauge reports whether this replica currently holds the leader
+// election lease. It is registered and scraped on every replica, even
+// non-leaders, so Prometheus users can see the whole set.
+var IsLeaderGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "hpa_exporter_is_leader",
+	Help: "1 if this replica is the current leader, 0 otherwise.",
+})
+
+func init() {
+	prometheus.MustRegister(IsLeaderGauge)
+}
+
+// leading gates the condition-logging event handlers. It defaults to true
+// so single-replica deployments (the common case, leader election off)
+// behave exactly as before. main() flips it to false before leader
+// election starts, and the elected/OnStartedLeading callback flips it back.
+var leading int32 = 1
+
+// SetLeading lets main() mark this replica as a non-leader before the
+// leader-election loop starts, so condition logging doesn't run between
+// startup and the first leadership callback.
+func SetLeading(v bool) {
+	var i int32
+	if v {
+		i = 1
+	}
+	atomic.StoreInt32(&leading, i)
+}
+
+func isLeading() bool {
+	return atomic.LoadInt32(&leading) == 1
+}
+
+// RunLeaderElection blocks running the leader-election loop using a
+// coordination/v1 Lease until ctx is canceled, releasing the lease on
+// cancellation. onStartedLeading/onStoppedLeading let the caller hook
+// additional startup/shutdown behavior.
+func RunLeaderElection(ctx context.Context, cfg *config.Config, onStartedLeading, onStoppedLeading func()) error {
+	id, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: meta_v1.ObjectMeta{
+			Name:      cfg.LeaderElectionID,
+			Namespace: cfg.LeaderElectionNamespace,
+		},
+		Client: kubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: id,
+		},
+	}
+	lec := leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				SetLeading(true)
+				IsLeaderGauge.Set(1)
+				onStartedLeading()
+			},
+			OnStoppedLeading: func() {
+				SetLeading(false)
+				IsLeaderGauge.Set(0)
+				onStoppedLeading()
+			},
+		},
+	}
+	// RunOrDie returns as soon as the lease is lost; contend again until
+	// ctx is canceled.
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, lec)
+	}
+	return nil
+}
@@ -1,32 +1,15 @@
 package metrics
 
 import (
-	"context"
-	"encoding/json"
-	"flag"
 	"fmt"
-	"time"
 
-	as_v1 "k8s.io/api/autoscaling/v1"
-	as_v2 "k8s.io/api/autoscaling/v2beta1"
+	as_v2 "k8s.io/api/autoscaling/v2"
 	core_v1 "k8s.io/api/core/v1"
-	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/shichanson/hpa-exporter/pkg/setting"
-)
-
-const (
-	defaultMetricsInterval  = 30
-	defaultConditionLogging = false
-	defaultLoggingTo        = "stdout"
-	defaultCWLogGroup       = "hpa-exporter"
-	defaultCWLogStream      = "condition-log"
-	defaultLoggingInterval  = 60
-	defaultAddr             = ":9296"
 )
 
 const RootDoc = `<html>
@@ -38,11 +21,6 @@ const RootDoc = `<html>
 </html>
 `
 
-type conditions struct {
-	Name       string                                   `json:"name"`
-	Conditions []as_v2.HorizontalPodAutoscalerCondition `json:"conditions"`
-}
-
 type commonMetrics struct {
 	Kind       string
 	Name       string
@@ -50,22 +28,19 @@ type commonMetrics struct {
 	Value      float64
 }
 
-var Addr = flag.String("listen-address", defaultAddr, "The address to listen on for HTTP requests.")
-var MetricsInterval = flag.Int("MetricsInterval", defaultMetricsInterval, "Interval to scrape HPA status.")
-var LoggingInterval = flag.Int("loggingInterval", defaultLoggingInterval, "Interval to logging HPA conditions.")
-var ConditionLogging = flag.Bool("conditionLogging", defaultConditionLogging, "Logging HPA conditions.")
-var LoggingTo = flag.String("loggingTo", defaultLoggingTo, "Where to log. (stdout or cwlogs)")
-var cwLogGroup = flag.String("cwLogGroup", defaultCWLogGroup, "Name of CWLog group.")
-var cwLogStream = flag.String("cwLogStream", defaultCWLogStream, "Name of CWLog stream.")
-
-var kubeClient = setting.KubeClient
+var (
+	kubeClient     kubernetes.Interface
+	kubeRESTConfig *rest.Config
+)
 
-var cwSession = func() *cloudwatchlogs.CloudWatchLogs {
-	sess := session.Must(session.NewSessionWithOptions(session.Options{
-		SharedConfigState: session.SharedConfigEnable,
-	}))
-	return cloudwatchlogs.New(sess)
-}()
+// Configure wires the Kubernetes client and REST config used by every
+// constructor in this package (informers, leader election, the dynamic
+// client for custom resources). It must be called once, from main(),
+// before any other exported constructor.
+func Configure(client kubernetes.Interface, restConfig *rest.Config) {
+	kubeClient = client
+	kubeRESTConfig = restConfig
+}
 
 var baseLabels = []string{
 	"hpa_name",
@@ -87,128 +62,25 @@ var annoLabels = []string{
 	"cond_message",
 }
 
-var (
-	HpaCurrentPodsNum = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "hpa_current_pods_num",
-			Help: "Number of current pods by status.",
-		},
-		baseLabels,
-	)
-
-	HpaDesiredPodsNum = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "hpa_desired_pods_num",
-			Help: "Number of desired pods by status.",
-		},
-		baseLabels,
-	)
-
-	HpaMinPodsNum = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "hpa_min_pods_num",
-			Help: "Number of min pods by spec.",
-		},
-		baseLabels,
-	)
-
-	HpaMaxPodsNum = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "hpa_max_pods_num",
-			Help: "Number of max pods by spec.",
-		},
-		baseLabels,
-	)
-
-	HpaLastScaleSecond = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "hpa_last_scale_second",
-			Help: "Time the scale was last executed.",
-		},
-		baseLabels,
-	)
-
-	HpaCurrentMetricsValue = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "hpa_current_metrics_value",
-			Help: "Current Metrics Value.",
-		},
-		append(baseLabels, metricLabels...),
-	)
-
-	HpaTargetMetricsValue = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "hpa_target_metrics_value",
-			Help: "Target Metrics Value.",
-		},
-		append(baseLabels, metricLabels...),
-	)
-
-	HpaAbleToScale = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "hpa_able_to_scale",
-			Help: "status able to scale from annotation.",
-		},
-		append(baseLabels, annoLabels...),
-	)
-
-	HpaScalingActive = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "hpa_scaling_active",
-			Help: "status scaling active from annotation.",
-		},
-		append(baseLabels, annoLabels...),
-	)
-
-	HpaScalingLimited = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "hpa_scaling_limited",
-			Help: "status scaling limited from annotation.",
-		},
-		append(baseLabels, annoLabels...),
-	)
-)
-
-var collectors = []prometheus.Collector{
-	HpaCurrentPodsNum,
-	HpaDesiredPodsNum,
-	HpaMinPodsNum,
-	HpaMaxPodsNum,
-	HpaLastScaleSecond,
-	HpaCurrentMetricsValue,
-	HpaTargetMetricsValue,
-	HpaAbleToScale,
-	HpaScalingActive,
-	HpaScalingLimited,
+var containerResourceLabels = []string{
+	"container",
 }
 
-func init() {
-	prometheus.MustRegister(collectors...)
-}
-
-func ResetAllMetric() {
-	for _, c := range collectors {
-		if v, ok := c.(*prometheus.GaugeVec); ok {
-			v.Reset()
-		}
-	}
+var scalePolicyLabels = []string{
+	"direction",
+	"policy_type",
+	"select_policy",
 }
 
-func ValidateFlags() error {
-	if !(*LoggingTo == "stdout" || *LoggingTo == "cwlogs") {
-		return fmt.Errorf("invalid value `%s` of flag `loggingTo`, specify either `stdout` or `cwlogs`", *LoggingTo)
+// ValidateLoggingTo checks --logging-to against the sinks NewConditionSink
+// knows how to build.
+func ValidateLoggingTo(loggingTo string) error {
+	switch loggingTo {
+	case "stdout", "cwlogs", "file", "otlp-logs":
+		return nil
+	default:
+		return fmt.Errorf("invalid value `%s` of flag `logging-to`, specify one of `stdout`, `cwlogs`, `file`, `otlp-logs`", loggingTo)
 	}
-	return nil
-}
-
-func getHpaList() ([]as_v1.HorizontalPodAutoscaler, error) {
-	out, err := kubeClient.AutoscalingV1().HorizontalPodAutoscalers("").List(context.TODO(),meta_v1.ListOptions{})
-	return out.Items, err
-}
-
-func GetHpaListV2() ([]as_v2.HorizontalPodAutoscaler, error) {
-	out, err := kubeClient.AutoscalingV2beta1().HorizontalPodAutoscalers("").List(context.TODO(),meta_v1.ListOptions{})
-	return out.Items, err
 }
 
 func MergeLabels(m1, m2 map[string]string) map[string]string {
@@ -244,12 +116,28 @@ func MakeAnnotationCondLabels(cond as_v2.HorizontalPodAutoscalerCondition) (prom
 	return labelForward, labelReverse
 }
 
+// metricTargetValue extracts the single numeric value out of a v2
+// MetricTarget/MetricValueStatus, preferring utilization, then average
+// value, then absolute value - only one of the three is ever set.
+func metricTargetValue(utilization *int32, averageValue, value *resource.Quantity) float64 {
+	switch {
+	case utilization != nil:
+		return float64(*utilization)
+	case averageValue != nil:
+		return float64(averageValue.MilliValue()) / 1000
+	case value != nil:
+		return float64(value.MilliValue()) / 1000
+	default:
+		return 0
+	}
+}
+
 func ParseObjectSpec(m *as_v2.ObjectMetricSource) commonMetrics {
 	return commonMetrics{
-		Kind:       m.Target.Kind,
-		Name:       m.Target.Name,
-		MetricName: m.MetricName,
-		Value:      float64(m.TargetValue.MilliValue()) / 1000,
+		Kind:       m.DescribedObject.Kind,
+		Name:       m.DescribedObject.Name,
+		MetricName: m.Metric.Name,
+		Value:      metricTargetValue(m.Target.AverageUtilization, m.Target.AverageValue, m.Target.Value),
 	}
 }
 
@@ -257,47 +145,44 @@ func ParsePodsSpec(m *as_v2.PodsMetricSource) commonMetrics {
 	return commonMetrics{
 		Kind:       "Pod",
 		Name:       "-",
-		MetricName: m.MetricName,
-		Value:      float64(m.TargetAverageValue.MilliValue()) / 1000,
+		MetricName: m.Metric.Name,
+		Value:      metricTargetValue(m.Target.AverageUtilization, m.Target.AverageValue, m.Target.Value),
 	}
 }
 
 func ParseResourceSpec(m *as_v2.ResourceMetricSource) commonMetrics {
-	var t float64
-	if m.TargetAverageUtilization == nil {
-		t = float64(m.TargetAverageValue.MilliValue()) / 1000
-	} else {
-		t = float64(*m.TargetAverageUtilization)
-	}
 	return commonMetrics{
 		Kind:       "Resource",
 		Name:       m.Name.String(),
 		MetricName: "-",
-		Value:      t,
+		Value:      metricTargetValue(m.Target.AverageUtilization, m.Target.AverageValue, m.Target.Value),
 	}
 }
 
+func ParseContainerResourceSpec(m *as_v2.ContainerResourceMetricSource) (commonMetrics, string) {
+	return commonMetrics{
+		Kind:       "ContainerResource",
+		Name:       m.Name.String(),
+		MetricName: "-",
+		Value:      metricTargetValue(m.Target.AverageUtilization, m.Target.AverageValue, m.Target.Value),
+	}, m.Container
+}
+
 func ParseExternalSpec(m *as_v2.ExternalMetricSource) commonMetrics {
-	var t float64
-	if m.TargetAverageValue == nil {
-		t = float64(m.TargetValue.MilliValue()) / 1000
-	} else {
-		t = float64(m.TargetAverageValue.MilliValue()) / 1000
-	}
 	return commonMetrics{
 		Kind:       "External",
 		Name:       "-",
-		MetricName: m.MetricName,
-		Value:      t,
+		MetricName: m.Metric.Name,
+		Value:      metricTargetValue(m.Target.AverageUtilization, m.Target.AverageValue, m.Target.Value),
 	}
 }
 
 func ParseObjectStatus(m *as_v2.ObjectMetricStatus) commonMetrics {
 	return commonMetrics{
-		Kind:       m.Target.Kind,
-		Name:       m.Target.Name,
-		MetricName: m.MetricName,
-		Value:      float64(m.CurrentValue.MilliValue()) / 1000,
+		Kind:       m.DescribedObject.Kind,
+		Name:       m.DescribedObject.Name,
+		MetricName: m.Metric.Name,
+		Value:      metricTargetValue(m.Current.AverageUtilization, m.Current.AverageValue, m.Current.Value),
 	}
 }
 
@@ -305,38 +190,35 @@ func ParsePodsStatus(m *as_v2.PodsMetricStatus) commonMetrics {
 	return commonMetrics{
 		Kind:       "Pod",
 		Name:       "-",
-		MetricName: m.MetricName,
-		Value:      float64(m.CurrentAverageValue.MilliValue()) / 1000,
+		MetricName: m.Metric.Name,
+		Value:      metricTargetValue(m.Current.AverageUtilization, m.Current.AverageValue, m.Current.Value),
 	}
 }
 
 func ParseResourceStatus(m *as_v2.ResourceMetricStatus) commonMetrics {
-	var t float64
-	if m.CurrentAverageUtilization == nil {
-		t = float64(m.CurrentAverageValue.MilliValue()) / 1000
-	} else {
-		t = float64(*m.CurrentAverageUtilization)
-	}
 	return commonMetrics{
 		Kind:       "Resource",
 		Name:       m.Name.String(),
 		MetricName: "-",
-		Value:      t,
+		Value:      metricTargetValue(m.Current.AverageUtilization, m.Current.AverageValue, m.Current.Value),
 	}
 }
 
+func ParseContainerResourceStatus(m *as_v2.ContainerResourceMetricStatus) (commonMetrics, string) {
+	return commonMetrics{
+		Kind:       "ContainerResource",
+		Name:       m.Name.String(),
+		MetricName: "-",
+		Value:      metricTargetValue(m.Current.AverageUtilization, m.Current.AverageValue, m.Current.Value),
+	}, m.Container
+}
+
 func ParseExternalStatus(m *as_v2.ExternalMetricStatus) commonMetrics {
-	var t float64
-	if m.CurrentAverageValue == nil {
-		t = float64(m.CurrentValue.MilliValue()) / 1000
-	} else {
-		t = float64(m.CurrentAverageValue.MilliValue()) / 1000
-	}
 	return commonMetrics{
 		Kind:       "External",
 		Name:       "-",
-		MetricName: m.MetricName,
-		Value:      t,
+		MetricName: m.Metric.Name,
+		Value:      metricTargetValue(m.Current.AverageUtilization, m.Current.AverageValue, m.Current.Value),
 	}
 }
 
@@ -348,89 +230,50 @@ func ParseCommonMetrics(m commonMetrics) (float64, prometheus.Labels) {
 	}
 }
 
-func PutHPAConditionToCWLog(hpa []as_v2.HorizontalPodAutoscaler) error {
-	t, e := token()
-	if e != nil {
-		return e
+// ParseBehavior reports the stabilization window and every scaling policy
+// configured on a v2 HorizontalPodAutoscalerBehavior, one row per policy,
+// so the caller can set a gauge per direction/policy/select_policy tuple.
+// It returns nil windows and no policies when behavior is unset, and a nil
+// window and no policies for a direction that is unset.
+func ParseBehavior(b *as_v2.HorizontalPodAutoscalerBehavior) (scaleUpWindow, scaleDownWindow *int32, policies []ScalePolicyMetric) {
+	if b == nil {
+		return nil, nil, nil
 	}
-	cwevent := []*cloudwatchlogs.InputLogEvent{}
-	timestamp := aws.Int64(time.Now().Unix() * 1000)
-	for _, a := range hpa {
-		s := HpaConditionJsonString(a)
-		cwevent = append(cwevent, &cloudwatchlogs.InputLogEvent{
-			Message:   aws.String(s),
-			Timestamp: timestamp,
-		})
+	if b.ScaleUp != nil {
+		scaleUpWindow = b.ScaleUp.StabilizationWindowSeconds
+		policies = append(policies, scalingRulePolicies("up", b.ScaleUp)...)
 	}
-	putEvent := &cloudwatchlogs.PutLogEventsInput{
-		LogEvents:     cwevent,
-		LogGroupName:  cwLogGroup,
-		LogStreamName: cwLogStream,
-		SequenceToken: t,
-	}
-	//return contains only token `ret["NextSequenceToken"]`
-	_, err := cwSession.PutLogEvents(putEvent)
-	return err
-}
-
-func HpaConditionJsonString(hpa as_v2.HorizontalPodAutoscaler) string {
-	cond := conditions{
-		Name:       hpa.ObjectMeta.Name,
-		Conditions: hpa.Status.Conditions,
-	}
-	jsonBytes, err := json.Marshal(cond)
-	if err != nil {
-		fmt.Println("JSON Marshal error:", err)
-		return "{}"
-	}
-	return string(jsonBytes)
-}
-
-func token() (token *string, err error) {
-	input := &cloudwatchlogs.DescribeLogStreamsInput{
-		LogGroupName:        cwLogGroup,
-		LogStreamNamePrefix: cwLogStream,
-	}
-	x, err := cwSession.DescribeLogStreams(input)
-	if err == nil {
-		if len(x.LogStreams) == 0 {
-			err = createStream()
-		} else {
-			token = x.LogStreams[0].UploadSequenceToken
-		}
+	if b.ScaleDown != nil {
+		scaleDownWindow = b.ScaleDown.StabilizationWindowSeconds
+		policies = append(policies, scalingRulePolicies("down", b.ScaleDown)...)
 	}
 	return
 }
 
-func CheckLogGroup() error {
-	input := &cloudwatchlogs.DescribeLogGroupsInput{
-		LogGroupNamePrefix: cwLogGroup,
-	}
-	if r, e := cwSession.DescribeLogGroups(input); e == nil {
-		if len(r.LogGroups) == 0 {
-			if e := createLogGroup(); e != nil {
-				return e
-			}
-		}
-	} else {
-		return e
-	}
-	return nil
+// ScalePolicyMetric is one row of a HorizontalPodAutoscalerBehavior scaling
+// policy, ready to be emitted as descHpaScalePolicyValue/descHpaScalePolicyPeriodSeconds.
+type ScalePolicyMetric struct {
+	Direction    string
+	PolicyType   string
+	SelectPolicy string
+	Value        int32
+	Period       int32
 }
 
-func createLogGroup() error {
-	input := &cloudwatchlogs.CreateLogGroupInput{
-		LogGroupName: cwLogGroup,
+func scalingRulePolicies(direction string, rules *as_v2.HPAScalingRules) []ScalePolicyMetric {
+	selectPolicy := string(as_v2.MaxChangePolicySelect)
+	if rules.SelectPolicy != nil {
+		selectPolicy = string(*rules.SelectPolicy)
 	}
-	_, err := cwSession.CreateLogGroup(input)
-	return err
-}
-
-func createStream() error {
-	input := &cloudwatchlogs.CreateLogStreamInput{
-		LogGroupName:  cwLogGroup,
-		LogStreamName: cwLogStream,
+	out := make([]ScalePolicyMetric, 0, len(rules.Policies))
+	for _, p := range rules.Policies {
+		out = append(out, ScalePolicyMetric{
+			Direction:    direction,
+			PolicyType:   string(p.Type),
+			SelectPolicy: selectPolicy,
+			Value:        p.Value,
+			Period:       p.PeriodSeconds,
+		})
 	}
-	_, err := cwSession.CreateLogStream(input)
-	return err
-}
\ No newline at end of file
+	return out
+}
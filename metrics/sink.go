@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	as_v2 "k8s.io/api/autoscaling/v2"
+
+	"github.com/shichanson/hpa-exporter/pkg/config"
+)
+
+// HPAConditionEvent is a single HPA's condition snapshot, tagged with the
+// resource attributes every sink attaches regardless of backend.
+type HPAConditionEvent struct {
+	Namespace             string
+	HPAName               string
+	ScaleTargetKind       string
+	ScaleTargetName       string
+	ScaleTargetAPIVersion string
+	Conditions            []as_v2.HorizontalPodAutoscalerCondition
+}
+
+func conditionEventFromHPA(a as_v2.HorizontalPodAutoscaler) HPAConditionEvent {
+	return HPAConditionEvent{
+		Namespace:             a.ObjectMeta.Namespace,
+		HPAName:               a.ObjectMeta.Name,
+		ScaleTargetKind:       a.Spec.ScaleTargetRef.Kind,
+		ScaleTargetName:       a.Spec.ScaleTargetRef.Name,
+		ScaleTargetAPIVersion: a.Spec.ScaleTargetRef.APIVersion,
+		Conditions:            a.Status.Conditions,
+	}
+}
+
+// conditionBody shapes an HPAConditionEvent into the JSON record shared by
+// the cwlogs and file sinks: resource attributes plus one entry per
+// condition's type/status/reason/message.
+type conditionBody struct {
+	Namespace             string               `json:"k8s.namespace.name"`
+	HPAName               string               `json:"k8s.hpa.name"`
+	ScaleTargetKind       string               `json:"k8s.scaletarget.kind"`
+	ScaleTargetName       string               `json:"k8s.scaletarget.name"`
+	ScaleTargetAPIVersion string               `json:"k8s.scaletarget.apiversion"`
+	Conditions            []conditionBodyEntry `json:"conditions"`
+}
+
+type conditionBodyEntry struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func newConditionBody(e HPAConditionEvent) conditionBody {
+	entries := make([]conditionBodyEntry, 0, len(e.Conditions))
+	for _, c := range e.Conditions {
+		entries = append(entries, conditionBodyEntry{
+			Type:    string(c.Type),
+			Status:  string(c.Status),
+			Reason:  c.Reason,
+			Message: c.Message,
+		})
+	}
+	return conditionBody{
+		Namespace:             e.Namespace,
+		HPAName:               e.HPAName,
+		ScaleTargetKind:       e.ScaleTargetKind,
+		ScaleTargetName:       e.ScaleTargetName,
+		ScaleTargetAPIVersion: e.ScaleTargetAPIVersion,
+		Conditions:            entries,
+	}
+}
+
+// ConditionSink ships HPA condition events to a backend. Emit is called
+// with one batch of events at a time from the condition batcher's flush
+// loop (see RegisterConditionEventHandlers), not from the informer event
+// handlers themselves.
+type ConditionSink interface {
+	Emit(ctx context.Context, events []HPAConditionEvent) error
+	Close() error
+}
+
+// NewConditionSink builds the ConditionSink selected by cfg.LoggingTo.
+func NewConditionSink(logger *slog.Logger, cfg *config.Config) (ConditionSink, error) {
+	switch cfg.LoggingTo {
+	case "stdout":
+		return newStdoutSink(logger), nil
+	case "cwlogs":
+		return newCWLogsSink(cfg), nil
+	case "file":
+		return newFileSink(cfg), nil
+	case "otlp-logs":
+		return newOTLPLogsSink(cfg.OTLPLogsEndpoint)
+	default:
+		return nil, fmt.Errorf("invalid value `%s` of flag `logging-to`, specify one of `stdout`, `cwlogs`, `file`, `otlp-logs`", cfg.LoggingTo)
+	}
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+
+	"github.com/shichanson/hpa-exporter/pkg/config"
+)
+
+var cwSession = func() *cloudwatchlogs.CloudWatchLogs {
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	return cloudwatchlogs.New(sess)
+}()
+
+// cwLogsSink writes condition events as CloudWatch Logs events, one per
+// event, looking up the stream's upload sequence token before each batch.
+type cwLogsSink struct {
+	logGroup  string
+	logStream string
+}
+
+func newCWLogsSink(cfg *config.Config) *cwLogsSink {
+	return &cwLogsSink{logGroup: cfg.CWLogGroup, logStream: cfg.CWLogStream}
+}
+
+func (s *cwLogsSink) Emit(ctx context.Context, events []HPAConditionEvent) error {
+	t, e := s.token()
+	if e != nil {
+		return e
+	}
+	cwevent := []*cloudwatchlogs.InputLogEvent{}
+	timestamp := aws.Int64(time.Now().Unix() * 1000)
+	for _, ev := range events {
+		b, err := json.Marshal(newConditionBody(ev))
+		if err != nil {
+			return err
+		}
+		cwevent = append(cwevent, &cloudwatchlogs.InputLogEvent{
+			Message:   aws.String(string(b)),
+			Timestamp: timestamp,
+		})
+	}
+	putEvent := &cloudwatchlogs.PutLogEventsInput{
+		LogEvents:     cwevent,
+		LogGroupName:  aws.String(s.logGroup),
+		LogStreamName: aws.String(s.logStream),
+		SequenceToken: t,
+	}
+	//return contains only token `ret["NextSequenceToken"]`
+	_, err := cwSession.PutLogEvents(putEvent)
+	return err
+}
+
+func (s *cwLogsSink) Close() error { return nil }
+
+func (s *cwLogsSink) token() (token *string, err error) {
+	input := &cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName:        aws.String(s.logGroup),
+		LogStreamNamePrefix: aws.String(s.logStream),
+	}
+	x, err := cwSession.DescribeLogStreams(input)
+	if err == nil {
+		if len(x.LogStreams) == 0 {
+			err = s.createStream()
+		} else {
+			token = x.LogStreams[0].UploadSequenceToken
+		}
+	}
+	return
+}
+
+// CheckLogGroup ensures cfg.CWLogGroup exists, creating it if necessary.
+// Call this once at startup when --logging-to=cwlogs.
+func CheckLogGroup(logger *slog.Logger, cfg *config.Config) error {
+	input := &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: aws.String(cfg.CWLogGroup),
+	}
+	if r, e := cwSession.DescribeLogGroups(input); e == nil {
+		if len(r.LogGroups) == 0 {
+			logger.Info("creating CloudWatch log group", slog.String("logGroup", cfg.CWLogGroup))
+			if e := (&cwLogsSink{logGroup: cfg.CWLogGroup, logStream: cfg.CWLogStream}).createLogGroup(); e != nil {
+				return e
+			}
+		}
+	} else {
+		return e
+	}
+	return nil
+}
+
+func (s *cwLogsSink) createLogGroup() error {
+	input := &cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(s.logGroup),
+	}
+	_, err := cwSession.CreateLogGroup(input)
+	return err
+}
+
+func (s *cwLogsSink) createStream() error {
+	input := &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(s.logGroup),
+		LogStreamName: aws.String(s.logStream),
+	}
+	_, err := cwSession.CreateLogStream(input)
+	return err
+}
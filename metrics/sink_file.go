@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/shichanson/hpa-exporter/pkg/config"
+)
+
+// fileSink appends one JSON line per condition event to a rotating log
+// file, so condition history survives without any external dependency.
+type fileSink struct {
+	writer *lumberjack.Logger
+}
+
+func newFileSink(cfg *config.Config) *fileSink {
+	return &fileSink{writer: &lumberjack.Logger{
+		Filename:   cfg.LogFilePath,
+		MaxSize:    cfg.LogFileMaxSizeMB,
+		MaxBackups: cfg.LogFileMaxBackups,
+		MaxAge:     cfg.LogFileMaxAgeDays,
+	}}
+}
+
+func (s *fileSink) Emit(ctx context.Context, events []HPAConditionEvent) error {
+	for _, e := range events {
+		b, err := json.Marshal(newConditionBody(e))
+		if err != nil {
+			return err
+		}
+		b = append(b, '\n')
+		if _, err := s.writer.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fileSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+
+	otellog "go.opentelemetry.io/otel/log"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// otlpLogsSink ships condition events as OTLP log records, so they can land
+// in any OTLP-capable backend (Tempo, Grafana Cloud, Honeycomb, ...)
+// without any CloudWatch coupling.
+type otlpLogsSink struct {
+	provider *sdklog.LoggerProvider
+	logger   otellog.Logger
+}
+
+func newOTLPLogsSink(endpoint string) (*otlpLogsSink, error) {
+	ctx := context.Background()
+	exporter, err := otlploghttp.New(ctx,
+		otlploghttp.WithEndpoint(endpoint),
+		otlploghttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)))
+	return &otlpLogsSink{
+		provider: provider,
+		logger:   provider.Logger("hpa-exporter"),
+	}, nil
+}
+
+func (s *otlpLogsSink) Emit(ctx context.Context, events []HPAConditionEvent) error {
+	for _, e := range events {
+		b, err := json.Marshal(newConditionBody(e))
+		if err != nil {
+			return err
+		}
+		var record otellog.Record
+		record.SetBody(otellog.StringValue(string(b)))
+		record.AddAttributes(
+			otellog.KeyValue{Key: "k8s.namespace.name", Value: otellog.StringValue(e.Namespace)},
+			otellog.KeyValue{Key: "k8s.hpa.name", Value: otellog.StringValue(e.HPAName)},
+			otellog.KeyValue{Key: "k8s.scaletarget.kind", Value: otellog.StringValue(e.ScaleTargetKind)},
+			otellog.KeyValue{Key: "k8s.scaletarget.name", Value: otellog.StringValue(e.ScaleTargetName)},
+			otellog.KeyValue{Key: "k8s.scaletarget.apiversion", Value: otellog.StringValue(e.ScaleTargetAPIVersion)},
+		)
+		s.logger.Emit(ctx, record)
+	}
+	return nil
+}
+
+func (s *otlpLogsSink) Close() error {
+	return s.provider.Shutdown(context.Background())
+}
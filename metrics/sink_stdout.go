@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+)
+
+// stdoutSink is the original behavior: each condition event logged through
+// the shared structured logger.
+type stdoutSink struct {
+	logger *slog.Logger
+}
+
+func newStdoutSink(logger *slog.Logger) *stdoutSink {
+	return &stdoutSink{logger: logger}
+}
+
+func (s *stdoutSink) Emit(ctx context.Context, events []HPAConditionEvent) error {
+	for _, e := range events {
+		s.logger.Info("HPA condition",
+			slog.String("k8s.namespace.name", e.Namespace),
+			slog.String("k8s.hpa.name", e.HPAName),
+			slog.String("k8s.scaletarget.kind", e.ScaleTargetKind),
+			slog.String("k8s.scaletarget.name", e.ScaleTargetName),
+			slog.String("k8s.scaletarget.apiversion", e.ScaleTargetAPIVersion),
+			slog.Any("conditions", e.Conditions),
+		)
+	}
+	return nil
+}
+
+func (s *stdoutSink) Close() error { return nil }
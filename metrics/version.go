@@ -0,0 +1,245 @@
+package metrics
+
+import (
+	"sync"
+
+	as_v1 "k8s.io/api/autoscaling/v1"
+	as_v2 "k8s.io/api/autoscaling/v2"
+	as_v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	core_v1 "k8s.io/api/core/v1"
+)
+
+// autoscalingAPIVersion is the autoscaling group version served by the
+// cluster's API server, as discovered at runtime. Kubernetes 1.25 dropped
+// v2beta1, and 1.26 dropped v2beta2, so clusters in the wild may only have
+// one of v2, v2beta2 or v1 available.
+type autoscalingAPIVersion string
+
+const (
+	autoscalingV2      autoscalingAPIVersion = "v2"
+	autoscalingV2beta2 autoscalingAPIVersion = "v2beta2"
+	autoscalingV1      autoscalingAPIVersion = "v1"
+)
+
+var (
+	resolveVersionOnce sync.Once
+	resolvedAPIVersion autoscalingAPIVersion
+)
+
+// resolveAutoscalingAPIVersion asks the discovery client which autoscaling
+// group version the cluster serves and caches the result for the lifetime
+// of the process, preferring v2 and falling back to v2beta2 then v1.
+func resolveAutoscalingAPIVersion() autoscalingAPIVersion {
+	resolveVersionOnce.Do(func() {
+		resolvedAPIVersion = autoscalingV1
+
+		if _, err := kubeClient.Discovery().ServerResourcesForGroupVersion("autoscaling/v2"); err == nil {
+			resolvedAPIVersion = autoscalingV2
+			return
+		}
+		if _, err := kubeClient.Discovery().ServerResourcesForGroupVersion("autoscaling/v2beta2"); err == nil {
+			resolvedAPIVersion = autoscalingV2beta2
+			return
+		}
+	})
+	return resolvedAPIVersion
+}
+
+// convertV2beta2ToV2 copies a v2beta2 HPA into the equivalent v2 object.
+// v2beta2 introduced the Target/Metric struct layout that v2 later
+// stabilized, so every field maps across one-to-one.
+func convertV2beta2ToV2(in as_v2beta2.HorizontalPodAutoscaler) as_v2.HorizontalPodAutoscaler {
+	out := as_v2.HorizontalPodAutoscaler{
+		ObjectMeta: in.ObjectMeta,
+		Spec: as_v2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: as_v2.CrossVersionObjectReference(in.Spec.ScaleTargetRef),
+			MinReplicas:    in.Spec.MinReplicas,
+			MaxReplicas:    in.Spec.MaxReplicas,
+			Behavior:       convertBehaviorV2beta2ToV2(in.Spec.Behavior),
+		},
+		Status: as_v2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: in.Status.CurrentReplicas,
+			DesiredReplicas: in.Status.DesiredReplicas,
+			LastScaleTime:   in.Status.LastScaleTime,
+			Conditions:      convertConditionsV2beta2ToV2(in.Status.Conditions),
+		},
+	}
+	for _, m := range in.Spec.Metrics {
+		out.Spec.Metrics = append(out.Spec.Metrics, convertMetricSpecV2beta2ToV2(m))
+	}
+	for _, m := range in.Status.CurrentMetrics {
+		out.Status.CurrentMetrics = append(out.Status.CurrentMetrics, convertMetricStatusV2beta2ToV2(m))
+	}
+	return out
+}
+
+// convertV1ToV2 copies a v1 HPA into the v2 shape. v1 only supports a
+// single implicit CPU-utilization target, so it becomes a single Resource
+// metric with no Behavior and no Conditions (v1 status has none).
+func convertV1ToV2(in as_v1.HorizontalPodAutoscaler) as_v2.HorizontalPodAutoscaler {
+	out := as_v2.HorizontalPodAutoscaler{
+		ObjectMeta: in.ObjectMeta,
+		Spec: as_v2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: as_v2.CrossVersionObjectReference(in.Spec.ScaleTargetRef),
+			MinReplicas:    in.Spec.MinReplicas,
+			MaxReplicas:    in.Spec.MaxReplicas,
+		},
+		Status: as_v2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: in.Status.CurrentReplicas,
+			DesiredReplicas: in.Status.DesiredReplicas,
+			LastScaleTime:   in.Status.LastScaleTime,
+		},
+	}
+	if in.Spec.TargetCPUUtilizationPercentage != nil {
+		out.Spec.Metrics = []as_v2.MetricSpec{{
+			Type: as_v2.ResourceMetricSourceType,
+			Resource: &as_v2.ResourceMetricSource{
+				Name:   core_v1.ResourceCPU,
+				Target: as_v2.MetricTarget{Type: as_v2.UtilizationMetricType, AverageUtilization: in.Spec.TargetCPUUtilizationPercentage},
+			},
+		}}
+	}
+	if in.Status.CurrentCPUUtilizationPercentage != nil {
+		out.Status.CurrentMetrics = []as_v2.MetricStatus{{
+			Type: as_v2.ResourceMetricSourceType,
+			Resource: &as_v2.ResourceMetricStatus{
+				Name:    core_v1.ResourceCPU,
+				Current: as_v2.MetricValueStatus{AverageUtilization: in.Status.CurrentCPUUtilizationPercentage},
+			},
+		}}
+	}
+	return out
+}
+
+func convertConditionsV2beta2ToV2(in []as_v2beta2.HorizontalPodAutoscalerCondition) []as_v2.HorizontalPodAutoscalerCondition {
+	if in == nil {
+		return nil
+	}
+	out := make([]as_v2.HorizontalPodAutoscalerCondition, len(in))
+	for i, c := range in {
+		out[i] = as_v2.HorizontalPodAutoscalerCondition{
+			Type:               as_v2.HorizontalPodAutoscalerConditionType(c.Type),
+			Status:             c.Status,
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             c.Reason,
+			Message:            c.Message,
+		}
+	}
+	return out
+}
+
+func convertBehaviorV2beta2ToV2(in *as_v2beta2.HorizontalPodAutoscalerBehavior) *as_v2.HorizontalPodAutoscalerBehavior {
+	if in == nil {
+		return nil
+	}
+	return &as_v2.HorizontalPodAutoscalerBehavior{
+		ScaleUp:   convertScalingRulesV2beta2ToV2(in.ScaleUp),
+		ScaleDown: convertScalingRulesV2beta2ToV2(in.ScaleDown),
+	}
+}
+
+func convertScalingRulesV2beta2ToV2(in *as_v2beta2.HPAScalingRules) *as_v2.HPAScalingRules {
+	if in == nil {
+		return nil
+	}
+	out := &as_v2.HPAScalingRules{
+		StabilizationWindowSeconds: in.StabilizationWindowSeconds,
+	}
+	if in.SelectPolicy != nil {
+		p := as_v2.ScalingPolicySelect(*in.SelectPolicy)
+		out.SelectPolicy = &p
+	}
+	for _, p := range in.Policies {
+		out.Policies = append(out.Policies, as_v2.HPAScalingPolicy{
+			Type:          as_v2.HPAScalingPolicyType(p.Type),
+			Value:         p.Value,
+			PeriodSeconds: p.PeriodSeconds,
+		})
+	}
+	return out
+}
+
+func convertMetricSpecV2beta2ToV2(m as_v2beta2.MetricSpec) as_v2.MetricSpec {
+	out := as_v2.MetricSpec{Type: as_v2.MetricSourceType(m.Type)}
+	if m.Object != nil {
+		out.Object = &as_v2.ObjectMetricSource{
+			DescribedObject: as_v2.CrossVersionObjectReference(m.Object.DescribedObject),
+			Target:          convertMetricTargetV2beta2ToV2(m.Object.Target),
+			Metric:          as_v2.MetricIdentifier{Name: m.Object.Metric.Name, Selector: m.Object.Metric.Selector},
+		}
+	}
+	if m.Pods != nil {
+		out.Pods = &as_v2.PodsMetricSource{
+			Target: convertMetricTargetV2beta2ToV2(m.Pods.Target),
+			Metric: as_v2.MetricIdentifier{Name: m.Pods.Metric.Name, Selector: m.Pods.Metric.Selector},
+		}
+	}
+	if m.Resource != nil {
+		out.Resource = &as_v2.ResourceMetricSource{Name: m.Resource.Name, Target: convertMetricTargetV2beta2ToV2(m.Resource.Target)}
+	}
+	if m.ContainerResource != nil {
+		out.ContainerResource = &as_v2.ContainerResourceMetricSource{
+			Name:      m.ContainerResource.Name,
+			Container: m.ContainerResource.Container,
+			Target:    convertMetricTargetV2beta2ToV2(m.ContainerResource.Target),
+		}
+	}
+	if m.External != nil {
+		out.External = &as_v2.ExternalMetricSource{
+			Target: convertMetricTargetV2beta2ToV2(m.External.Target),
+			Metric: as_v2.MetricIdentifier{Name: m.External.Metric.Name, Selector: m.External.Metric.Selector},
+		}
+	}
+	return out
+}
+
+func convertMetricStatusV2beta2ToV2(m as_v2beta2.MetricStatus) as_v2.MetricStatus {
+	out := as_v2.MetricStatus{Type: as_v2.MetricSourceType(m.Type)}
+	if m.Object != nil {
+		out.Object = &as_v2.ObjectMetricStatus{
+			DescribedObject: as_v2.CrossVersionObjectReference(m.Object.DescribedObject),
+			Current:         convertMetricValueStatusV2beta2ToV2(m.Object.Current),
+			Metric:          as_v2.MetricIdentifier{Name: m.Object.Metric.Name, Selector: m.Object.Metric.Selector},
+		}
+	}
+	if m.Pods != nil {
+		out.Pods = &as_v2.PodsMetricStatus{
+			Current: convertMetricValueStatusV2beta2ToV2(m.Pods.Current),
+			Metric:  as_v2.MetricIdentifier{Name: m.Pods.Metric.Name, Selector: m.Pods.Metric.Selector},
+		}
+	}
+	if m.Resource != nil {
+		out.Resource = &as_v2.ResourceMetricStatus{Name: m.Resource.Name, Current: convertMetricValueStatusV2beta2ToV2(m.Resource.Current)}
+	}
+	if m.ContainerResource != nil {
+		out.ContainerResource = &as_v2.ContainerResourceMetricStatus{
+			Name:      m.ContainerResource.Name,
+			Container: m.ContainerResource.Container,
+			Current:   convertMetricValueStatusV2beta2ToV2(m.ContainerResource.Current),
+		}
+	}
+	if m.External != nil {
+		out.External = &as_v2.ExternalMetricStatus{
+			Current: convertMetricValueStatusV2beta2ToV2(m.External.Current),
+			Metric:  as_v2.MetricIdentifier{Name: m.External.Metric.Name, Selector: m.External.Metric.Selector},
+		}
+	}
+	return out
+}
+
+func convertMetricTargetV2beta2ToV2(t as_v2beta2.MetricTarget) as_v2.MetricTarget {
+	return as_v2.MetricTarget{
+		Type:               as_v2.MetricTargetType(t.Type),
+		Value:              t.Value,
+		AverageValue:       t.AverageValue,
+		AverageUtilization: t.AverageUtilization,
+	}
+}
+
+func convertMetricValueStatusV2beta2ToV2(v as_v2beta2.MetricValueStatus) as_v2.MetricValueStatus {
+	return as_v2.MetricValueStatus{
+		Value:              v.Value,
+		AverageValue:       v.AverageValue,
+		AverageUtilization: v.AverageUtilization,
+	}
+}
@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+
+	as_v1 "k8s.io/api/autoscaling/v1"
+	as_v2 "k8s.io/api/autoscaling/v2"
+	as_v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func int32ptr(v int32) *int32 { return &v }
+
+func TestConvertV1ToV2(t *testing.T) {
+	cases := []struct {
+		name string
+		in   as_v1.HorizontalPodAutoscaler
+		want as_v2.HorizontalPodAutoscaler
+	}{
+		{
+			name: "minimal, no CPU targets set",
+			in: as_v1.HorizontalPodAutoscaler{
+				ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: as_v1.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: as_v1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+					MinReplicas:    int32ptr(1),
+					MaxReplicas:    10,
+				},
+			},
+			want: as_v2.HorizontalPodAutoscaler{
+				ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: as_v2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: as_v2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+					MinReplicas:    int32ptr(1),
+					MaxReplicas:    10,
+				},
+			},
+		},
+		{
+			name: "CPU target and current utilization set",
+			in: as_v1.HorizontalPodAutoscaler{
+				ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: as_v1.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef:                 as_v1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+					MinReplicas:                    int32ptr(1),
+					MaxReplicas:                    10,
+					TargetCPUUtilizationPercentage: int32ptr(80),
+				},
+				Status: as_v1.HorizontalPodAutoscalerStatus{
+					CurrentReplicas:                 3,
+					DesiredReplicas:                 4,
+					CurrentCPUUtilizationPercentage: int32ptr(55),
+				},
+			},
+			want: as_v2.HorizontalPodAutoscaler{
+				ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: as_v2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: as_v2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+					MinReplicas:    int32ptr(1),
+					MaxReplicas:    10,
+					Metrics: []as_v2.MetricSpec{{
+						Type: as_v2.ResourceMetricSourceType,
+						Resource: &as_v2.ResourceMetricSource{
+							Name:   core_v1.ResourceCPU,
+							Target: as_v2.MetricTarget{Type: as_v2.UtilizationMetricType, AverageUtilization: int32ptr(80)},
+						},
+					}},
+				},
+				Status: as_v2.HorizontalPodAutoscalerStatus{
+					CurrentReplicas: 3,
+					DesiredReplicas: 4,
+					CurrentMetrics: []as_v2.MetricStatus{{
+						Type: as_v2.ResourceMetricSourceType,
+						Resource: &as_v2.ResourceMetricStatus{
+							Name:    core_v1.ResourceCPU,
+							Current: as_v2.MetricValueStatus{AverageUtilization: int32ptr(55)},
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := convertV1ToV2(tc.in)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("convertV1ToV2() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConvertV2beta2ToV2(t *testing.T) {
+	selectPolicy := as_v2beta2.MaxPolicySelect
+	in := as_v2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: as_v2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: as_v2beta2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+			MinReplicas:    int32ptr(2),
+			MaxReplicas:    20,
+			Metrics: []as_v2beta2.MetricSpec{{
+				Type: as_v2beta2.ResourceMetricSourceType,
+				Resource: &as_v2beta2.ResourceMetricSource{
+					Name:   core_v1.ResourceCPU,
+					Target: as_v2beta2.MetricTarget{Type: as_v2beta2.UtilizationMetricType, AverageUtilization: int32ptr(70)},
+				},
+			}},
+			Behavior: &as_v2beta2.HorizontalPodAutoscalerBehavior{
+				ScaleUp: &as_v2beta2.HPAScalingRules{
+					StabilizationWindowSeconds: int32ptr(60),
+					SelectPolicy:               &selectPolicy,
+					Policies: []as_v2beta2.HPAScalingPolicy{
+						{Type: as_v2beta2.PodsScalingPolicy, Value: 4, PeriodSeconds: 15},
+					},
+				},
+			},
+		},
+		Status: as_v2beta2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: 5,
+			DesiredReplicas: 8,
+			Conditions: []as_v2beta2.HorizontalPodAutoscalerCondition{
+				{Type: as_v2beta2.AbleToScale, Status: core_v1.ConditionTrue, Reason: "ReadyForNewScale", Message: "ok"},
+			},
+		},
+	}
+
+	want := as_v2.HorizontalPodAutoscaler{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: as_v2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: as_v2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+			MinReplicas:    int32ptr(2),
+			MaxReplicas:    20,
+			Metrics: []as_v2.MetricSpec{{
+				Type: as_v2.ResourceMetricSourceType,
+				Resource: &as_v2.ResourceMetricSource{
+					Name:   core_v1.ResourceCPU,
+					Target: as_v2.MetricTarget{Type: as_v2.UtilizationMetricType, AverageUtilization: int32ptr(70)},
+				},
+			}},
+			Behavior: &as_v2.HorizontalPodAutoscalerBehavior{
+				ScaleUp: &as_v2.HPAScalingRules{
+					StabilizationWindowSeconds: int32ptr(60),
+					SelectPolicy:               (*as_v2.ScalingPolicySelect)(&selectPolicy),
+					Policies: []as_v2.HPAScalingPolicy{
+						{Type: as_v2.PodsScalingPolicy, Value: 4, PeriodSeconds: 15},
+					},
+				},
+			},
+		},
+		Status: as_v2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: 5,
+			DesiredReplicas: 8,
+			Conditions: []as_v2.HorizontalPodAutoscalerCondition{
+				{Type: as_v2.AbleToScale, Status: core_v1.ConditionTrue, Reason: "ReadyForNewScale", Message: "ok"},
+			},
+		},
+	}
+
+	got := convertV2beta2ToV2(in)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("convertV2beta2ToV2() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,228 @@
+// Package config parses every hpa-exporter flag exactly once, in main(),
+// replacing the scattered package-level flag.Var declarations (and the
+// flag.Parse() call that used to run inside pkg/setting's init(), racing
+// main()'s own flag.Parse() and silently swallowing unknown flags).
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Config holds every flag the exporter accepts. Each flag also falls back
+// to an HPA_EXPORTER_* environment variable, and --config.file supplies
+// defaults that explicit flags/env vars still override.
+type Config struct {
+	ListenAddress string
+	WebConfigFile string
+	ConfigFile    string
+
+	Kubeconfig    string
+	InCluster     bool
+	Namespace     string
+	LabelSelector string
+	ResyncPeriod  time.Duration
+
+	ConditionLogging bool
+	LoggingTo        string
+
+	CWLogGroup  string
+	CWLogStream string
+
+	LogFilePath       string
+	LogFileMaxSizeMB  int
+	LogFileMaxBackups int
+	LogFileMaxAgeDays int
+	OTLPLogsEndpoint  string
+
+	CustomResourceConfigFile string
+
+	EnableLeaderElection    bool
+	LeaderElectionNamespace string
+	LeaderElectionID        string
+
+	LogLevel  string
+	LogFormat string
+}
+
+// fileDefaults is the subset of Config that --config.file can pre-seed, in
+// the same shape as the flags themselves.
+type fileDefaults struct {
+	ListenAddress string `json:"listen-address"`
+	WebConfigFile string `json:"web.config.file"`
+
+	Kubeconfig    string `json:"kubeconfig"`
+	InCluster     *bool  `json:"in-cluster"`
+	Namespace     string `json:"namespace"`
+	LabelSelector string `json:"label-selector"`
+	ResyncPeriod  string `json:"resync-period"`
+
+	ConditionLogging *bool  `json:"conditionLogging"`
+	LoggingTo        string `json:"logging-to"`
+
+	CWLogGroup  string `json:"cwLogGroup"`
+	CWLogStream string `json:"cwLogStream"`
+
+	LogFilePath       string `json:"logFilePath"`
+	LogFileMaxSizeMB  *int   `json:"logFileMaxSizeMB"`
+	LogFileMaxBackups *int   `json:"logFileMaxBackups"`
+	LogFileMaxAgeDays *int   `json:"logFileMaxAgeDays"`
+	OTLPLogsEndpoint  string `json:"otlpLogsEndpoint"`
+
+	CustomResourceConfigFile string `json:"custom-resource-config"`
+
+	EnableLeaderElection    *bool  `json:"enable-leader-election"`
+	LeaderElectionNamespace string `json:"leader-election-namespace"`
+	LeaderElectionID        string `json:"leader-election-id"`
+
+	LogLevel  string `json:"log.level"`
+	LogFormat string `json:"log.format"`
+}
+
+// New parses args (os.Args[1:] in production) into a Config. --config.file
+// is scanned for up front, since it seeds the kingpin flag defaults that
+// the real flag/env parse below can still override.
+func New(args []string) (*Config, error) {
+	def, err := loadFileDefaults(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	app := kingpin.New("hpa-exporter", "Prometheus exporter for HorizontalPodAutoscaler status.")
+
+	app.Flag("listen-address", "The address to listen on for HTTP requests.").
+		Envar("HPA_EXPORTER_LISTEN_ADDRESS").Default(def.str(def.ListenAddress, ":9296")).StringVar(&cfg.ListenAddress)
+	app.Flag("web.config.file", "Path to a web config file enabling TLS and/or basic auth on the web server, per github.com/prometheus/exporter-toolkit/web.").
+		Envar("HPA_EXPORTER_WEB_CONFIG_FILE").Default(def.WebConfigFile).StringVar(&cfg.WebConfigFile)
+	app.Flag("config.file", "Path to a YAML file of default flag values; explicit flags/env vars still win.").
+		Envar("HPA_EXPORTER_CONFIG_FILE").StringVar(&cfg.ConfigFile)
+
+	app.Flag("kubeconfig", "Path to a kubeconfig file. Ignored when --in-cluster is set.").
+		Envar("HPA_EXPORTER_KUBECONFIG").Default(def.str(def.Kubeconfig, defaultKubeconfig())).StringVar(&cfg.Kubeconfig)
+	app.Flag("in-cluster", "Use the in-cluster Kubernetes config instead of --kubeconfig.").
+		Envar("HPA_EXPORTER_IN_CLUSTER").Default(def.bl(def.InCluster, false)).BoolVar(&cfg.InCluster)
+	app.Flag("namespace", "Restrict the HPA informer to a single namespace (default: all namespaces).").
+		Envar("HPA_EXPORTER_NAMESPACE").Default(def.Namespace).StringVar(&cfg.Namespace)
+	app.Flag("label-selector", "Label selector used to filter which HPAs are watched.").
+		Envar("HPA_EXPORTER_LABEL_SELECTOR").Default(def.LabelSelector).StringVar(&cfg.LabelSelector)
+	app.Flag("resync-period", "Full resync period for the HPA informer.").
+		Envar("HPA_EXPORTER_RESYNC_PERIOD").Default(def.str(def.ResyncPeriod, "5m")).DurationVar(&cfg.ResyncPeriod)
+
+	app.Flag("conditionLogging", "Logging HPA conditions.").
+		Envar("HPA_EXPORTER_CONDITION_LOGGING").Default(def.bl(def.ConditionLogging, false)).BoolVar(&cfg.ConditionLogging)
+	app.Flag("logging-to", "Where to log HPA conditions. (stdout, cwlogs, file or otlp-logs)").
+		Envar("HPA_EXPORTER_LOGGING_TO").Default(def.str(def.LoggingTo, "stdout")).StringVar(&cfg.LoggingTo)
+
+	app.Flag("cwLogGroup", "Name of CWLog group.").
+		Envar("HPA_EXPORTER_CW_LOG_GROUP").Default(def.str(def.CWLogGroup, "hpa-exporter")).StringVar(&cfg.CWLogGroup)
+	app.Flag("cwLogStream", "Name of CWLog stream.").
+		Envar("HPA_EXPORTER_CW_LOG_STREAM").Default(def.str(def.CWLogStream, "condition-log")).StringVar(&cfg.CWLogStream)
+
+	app.Flag("logFilePath", "Path of the JSON-lines file written by the `file` logging-to sink.").
+		Envar("HPA_EXPORTER_LOG_FILE_PATH").Default(def.str(def.LogFilePath, "hpa-conditions.log")).StringVar(&cfg.LogFilePath)
+	app.Flag("logFileMaxSizeMB", "Max size in megabytes of a condition log file before it's rotated.").
+		Envar("HPA_EXPORTER_LOG_FILE_MAX_SIZE_MB").Default(def.intStr(def.LogFileMaxSizeMB, 100)).IntVar(&cfg.LogFileMaxSizeMB)
+	app.Flag("logFileMaxBackups", "Max number of rotated condition log files to keep.").
+		Envar("HPA_EXPORTER_LOG_FILE_MAX_BACKUPS").Default(def.intStr(def.LogFileMaxBackups, 3)).IntVar(&cfg.LogFileMaxBackups)
+	app.Flag("logFileMaxAgeDays", "Max age in days to keep a rotated condition log file.").
+		Envar("HPA_EXPORTER_LOG_FILE_MAX_AGE_DAYS").Default(def.intStr(def.LogFileMaxAgeDays, 28)).IntVar(&cfg.LogFileMaxAgeDays)
+	app.Flag("otlpLogsEndpoint", "OTLP/HTTP endpoint used by the `otlp-logs` logging-to sink.").
+		Envar("HPA_EXPORTER_OTLP_LOGS_ENDPOINT").Default(def.str(def.OTLPLogsEndpoint, "localhost:4318")).StringVar(&cfg.OTLPLogsEndpoint)
+
+	app.Flag("custom-resource-config", "Path to a customresourcestate-style YAML config for extra scalable resources (e.g. KEDA ScaledObjects, VPAs). Defaults to a built-in config covering ScaledObject and VerticalPodAutoscaler.").
+		Envar("HPA_EXPORTER_CUSTOM_RESOURCE_CONFIG").Default(def.CustomResourceConfigFile).StringVar(&cfg.CustomResourceConfigFile)
+
+	app.Flag("enable-leader-election", "Run with leader election so only one replica logs HPA conditions.").
+		Envar("HPA_EXPORTER_ENABLE_LEADER_ELECTION").Default(def.bl(def.EnableLeaderElection, false)).BoolVar(&cfg.EnableLeaderElection)
+	app.Flag("leader-election-namespace", "Namespace of the Lease object used for leader election.").
+		Envar("HPA_EXPORTER_LEADER_ELECTION_NAMESPACE").Default(def.str(def.LeaderElectionNamespace, "default")).StringVar(&cfg.LeaderElectionNamespace)
+	app.Flag("leader-election-id", "Name of the Lease object used for leader election.").
+		Envar("HPA_EXPORTER_LEADER_ELECTION_ID").Default(def.str(def.LeaderElectionID, "hpa-exporter-leader")).StringVar(&cfg.LeaderElectionID)
+
+	app.Flag("log.level", "Only log messages with the given severity or above. One of: [debug, info, warn, error]").
+		Envar("HPA_EXPORTER_LOG_LEVEL").Default(def.str(def.LogLevel, "info")).StringVar(&cfg.LogLevel)
+	app.Flag("log.format", "Output format of log messages. One of: [logfmt, json]").
+		Envar("HPA_EXPORTER_LOG_FORMAT").Default(def.str(def.LogFormat, "logfmt")).StringVar(&cfg.LogFormat)
+
+	if _, err := app.Parse(args); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadFileDefaults scans args for --config.file without fully parsing
+// (kingpin flags aren't registered yet) and, if present, unmarshals it.
+func loadFileDefaults(args []string) (fileDefaults, error) {
+	path := scanConfigFileFlag(args)
+	if path == "" {
+		path = os.Getenv("HPA_EXPORTER_CONFIG_FILE")
+	}
+	if path == "" {
+		return fileDefaults{}, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fileDefaults{}, fmt.Errorf("reading --config.file: %w", err)
+	}
+	var def fileDefaults
+	if err := yaml.Unmarshal(b, &def); err != nil {
+		return fileDefaults{}, fmt.Errorf("parsing --config.file: %w", err)
+	}
+	return def, nil
+}
+
+func scanConfigFileFlag(args []string) string {
+	const prefix = "--config.file="
+	for i, a := range args {
+		if a == "--config.file" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if len(a) > len(prefix) && a[:len(prefix)] == prefix {
+			return a[len(prefix):]
+		}
+	}
+	return ""
+}
+
+func defaultKubeconfig() string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	return home + "/.kube/config"
+}
+
+func (fileDefaults) str(v, fallback string) string {
+	if v != "" {
+		return v
+	}
+	return fallback
+}
+
+// bl renders a --config.file bool as a kingpin default, falling back to
+// fallback only when the key was absent from the file (v == nil) rather
+// than whenever it's false, so an explicit `false` in the file still wins.
+func (fileDefaults) bl(v *bool, fallback bool) string {
+	if v != nil {
+		fallback = *v
+	}
+	if fallback {
+		return "true"
+	}
+	return "false"
+}
+
+// intStr renders a --config.file int as a kingpin default, falling back to
+// fallback only when the key was absent from the file (v == nil) rather
+// than whenever it's zero, so an explicit `0` in the file still wins.
+func (fileDefaults) intStr(v *int, fallback int) string {
+	if v != nil {
+		fallback = *v
+	}
+	return fmt.Sprintf("%d", fallback)
+}
@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+)
+
+// New builds a *slog.Logger from --log.level and --log.format, writing to
+// stderr like the rest of the Prometheus ecosystem. Logfmt output uses
+// slog's built-in text handler, which already renders key=value pairs.
+func New(level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(level)}
+
+	var handler slog.Handler
+	if format == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	return slog.New(handler)
+}
+
+func parseLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}